@@ -0,0 +1,79 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/AliseMarfina/task-5/pkg/topology"
+)
+
+const standardStream = "-"
+
+var ErrInvalidFlag = errors.New("invalid flag value")
+
+// stageFlags collects -stage values of the form type:handler:inputs:outputs,
+// where inputs and outputs are comma-separated channel names.
+type stageFlags []topology.Node
+
+func (s *stageFlags) String() string {
+	stages := make([]string, 0, len(*s))
+
+	for _, node := range *s {
+		stages = append(stages, strings.Join([]string{
+			node.Type, node.Handler, strings.Join(node.Inputs, ","), strings.Join(node.Outputs, ","),
+		}, ":"))
+	}
+
+	return strings.Join(stages, " ")
+}
+
+func (s *stageFlags) Set(value string) error {
+	parts := strings.Split(value, ":")
+	if len(parts) != 4 {
+		return fmt.Errorf("%w: stage %q is not type:handler:inputs:outputs", ErrInvalidFlag, value)
+	}
+
+	*s = append(*s, topology.Node{
+		Type:    parts[0],
+		Handler: parts[1],
+		Inputs:  strings.Split(parts[2], ","),
+		Outputs: strings.Split(parts[3], ","),
+	})
+
+	return nil
+}
+
+type binding struct {
+	channel string
+	path    string
+}
+
+// bindingFlags collects channel[=path] values; a missing path or "-" stands for
+// stdin or stdout.
+type bindingFlags []binding
+
+func (b *bindingFlags) String() string {
+	bindings := make([]string, 0, len(*b))
+
+	for _, bound := range *b {
+		bindings = append(bindings, bound.channel+"="+bound.path)
+	}
+
+	return strings.Join(bindings, " ")
+}
+
+func (b *bindingFlags) Set(value string) error {
+	channel, path, hasPath := strings.Cut(value, "=")
+	if channel == "" || (hasPath && path == "") {
+		return fmt.Errorf("%w: %q is not channel[=path]", ErrInvalidFlag, value)
+	}
+
+	if !hasPath {
+		path = standardStream
+	}
+
+	*b = append(*b, binding{channel: channel, path: path})
+
+	return nil
+}
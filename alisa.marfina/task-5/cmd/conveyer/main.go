@@ -0,0 +1,215 @@
+// Command conveyer runs a pipeline built from a topology file or -stage flags.
+// Lines read from stdin or files are sent to the input channels and every
+// message of an output channel is written as a line to stdout or a file. With
+// -stage, channels no stage writes to are inputs and channels no stage reads
+// from are outputs.
+//
+// The exit status is 0 on success, 1 when Run or the I/O fails, 2 for invalid
+// flags or pipelines and 130 when interrupted.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+
+	"github.com/AliseMarfina/task-5/pkg/topology"
+)
+
+const (
+	exitOK          = 0
+	exitFailure     = 1
+	exitUsage       = 2
+	exitInterrupted = 130
+
+	defaultBufferSize = 64
+)
+
+var (
+	ErrNoPipeline          = errors.New("either -topology or -stage is required")
+	ErrConflictingPipeline = errors.New("-topology and -stage cannot be combined")
+	ErrNoInputs            = errors.New("pipeline has no input channels")
+	ErrNoOutputs           = errors.New("pipeline has no output channels")
+	ErrSharedStdin         = errors.New("only one input channel can read stdin")
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+
+	stop()
+	os.Exit(code)
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	var (
+		topologyPath string
+		bufferSize   int
+		stages       stageFlags
+		inputs       bindingFlags
+		outputs      bindingFlags
+	)
+
+	flags := flag.NewFlagSet("conveyer", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&topologyPath, "topology", "", "Path to a YAML or JSON topology file")
+	flags.IntVar(&bufferSize, "buffer", defaultBufferSize, "Channel buffer size for pipelines built with -stage")
+	flags.Var(&stages, "stage", "Stage as type:handler:inputs:outputs, e.g. decorator:prefix-decorator:in:out (repeatable)")
+	flags.Var(&inputs, "input", "Input channel as channel[=path]; unbound inputs read stdin (repeatable)")
+	flags.Var(&outputs, "output", "Output channel as channel[=path]; unbound outputs write stdout (repeatable)")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+
+		return exitUsage
+	}
+
+	spec, err := pipelineSpec(topologyPath, bufferSize, stages, inputs, outputs)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+
+		return exitUsage
+	}
+
+	conv, err := topology.Build(spec, topology.DefaultRegistry())
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+
+		return exitUsage
+	}
+
+	sources, sinks, closeStreams, err := openStreams(spec, inputs, outputs, stdin, stdout)
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+
+		return exitFailure
+	}
+
+	err = errors.Join(execute(ctx, conv, sources, sinks), closeStreams())
+
+	switch {
+	case ctx.Err() != nil:
+		return exitInterrupted
+	case err != nil:
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+
+		return exitFailure
+	default:
+		return exitOK
+	}
+}
+
+// pipelineSpec turns the flags into a topology whose inputs and outputs also
+// include every channel bound by -input or -output.
+func pipelineSpec(
+	topologyPath string,
+	bufferSize int,
+	stages stageFlags,
+	inputs, outputs bindingFlags,
+) (topology.Topology, error) {
+	var spec topology.Topology
+
+	switch {
+	case topologyPath != "" && len(stages) > 0:
+		return topology.Topology{}, ErrConflictingPipeline
+
+	case topologyPath != "":
+		read, err := topology.ReadFile(topologyPath)
+		if err != nil {
+			return topology.Topology{}, err
+		}
+
+		spec = read
+
+	case len(stages) > 0:
+		sources, sinks := graphEnds(stages)
+		spec = topology.Topology{BufferSize: bufferSize, Inputs: sources, Outputs: sinks, Nodes: stages}
+
+	default:
+		return topology.Topology{}, ErrNoPipeline
+	}
+
+	spec.Inputs = withBound(spec.Inputs, inputs)
+	spec.Outputs = withBound(spec.Outputs, outputs)
+
+	if len(spec.Inputs) == 0 {
+		return topology.Topology{}, ErrNoInputs
+	}
+
+	if len(spec.Outputs) == 0 {
+		return topology.Topology{}, ErrNoOutputs
+	}
+
+	readStdin := 0
+
+	for _, channel := range spec.Inputs {
+		if boundPath(inputs, channel) == standardStream {
+			readStdin++
+		}
+	}
+
+	if readStdin > 1 {
+		return topology.Topology{}, ErrSharedStdin
+	}
+
+	return spec, nil
+}
+
+// graphEnds lists, in order of appearance, the channels no stage writes to and
+// the channels no stage reads from.
+func graphEnds(nodes []topology.Node) ([]string, []string) {
+	read, written := make(map[string]bool), make(map[string]bool)
+
+	for _, node := range nodes {
+		for _, channel := range node.Inputs {
+			read[channel] = true
+		}
+
+		for _, channel := range node.Outputs {
+			written[channel] = true
+		}
+	}
+
+	var sources, sinks []string
+
+	for _, node := range nodes {
+		for _, channel := range node.Inputs {
+			if !written[channel] && !slices.Contains(sources, channel) {
+				sources = append(sources, channel)
+			}
+		}
+
+		for _, channel := range node.Outputs {
+			if !read[channel] && !slices.Contains(sinks, channel) {
+				sinks = append(sinks, channel)
+			}
+		}
+	}
+
+	return sources, sinks
+}
+
+func withBound(channels []string, bindings bindingFlags) []string {
+	known := make(map[string]bool, len(channels))
+
+	for _, channel := range channels {
+		known[channel] = true
+	}
+
+	for _, bound := range bindings {
+		if !known[bound.channel] {
+			known[bound.channel] = true
+			channels = append(channels, bound.channel)
+		}
+	}
+
+	return channels
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const prefixStage = "decorator:prefix-decorator:in:out"
+
+func TestRun_ExitCodes(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		args   []string
+		stdin  string
+		code   int
+		stdout string
+		stderr string
+	}{
+		{
+			name:   "inputs and outputs inferred from stages",
+			args:   []string{"-stage", prefixStage},
+			stdin:  "a\nb\n",
+			code:   exitOK,
+			stdout: "decorated: a\ndecorated: b\n",
+			stderr: "",
+		},
+		{
+			name:   "handler fails",
+			args:   []string{"-stage", prefixStage},
+			stdin:  "no decorator\n",
+			code:   exitFailure,
+			stdout: "",
+			stderr: "can't be decorated",
+		},
+		{
+			name:   "no pipeline",
+			args:   nil,
+			stdin:  "",
+			code:   exitUsage,
+			stdout: "",
+			stderr: ErrNoPipeline.Error(),
+		},
+		{
+			name:   "malformed stage",
+			args:   []string{"-stage", "decorator:prefix-decorator"},
+			stdin:  "",
+			code:   exitUsage,
+			stdout: "",
+			stderr: "not type:handler:inputs:outputs",
+		},
+		{
+			name:   "two inputs on stdin",
+			args:   []string{"-stage", "multiplexer:multiplexer:left,right:out"},
+			stdin:  "",
+			code:   exitUsage,
+			stdout: "",
+			stderr: ErrSharedStdin.Error(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var stdout, stderr bytes.Buffer
+
+			code := run(context.Background(), test.args, strings.NewReader(test.stdin), &stdout, &stderr)
+
+			assert.Equal(t, test.code, code, stderr.String())
+			assert.Equal(t, test.stdout, stdout.String())
+			assert.Contains(t, stderr.String(), test.stderr)
+		})
+	}
+}
+
+func TestRun_FileBindings(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	left, right := filepath.Join(dir, "left.txt"), filepath.Join(dir, "right.txt")
+	output := filepath.Join(dir, "out.txt")
+
+	require.NoError(t, os.WriteFile(left, []byte("a\n"), 0o600))
+	require.NoError(t, os.WriteFile(right, []byte("b\n"), 0o600))
+
+	var stdout, stderr bytes.Buffer
+
+	code := run(context.Background(), []string{
+		"-stage", "multiplexer:multiplexer:left,right:out",
+		"-input", "left=" + left,
+		"-input", "right=" + right,
+		"-output", "out=" + output,
+	}, strings.NewReader(""), &stdout, &stderr)
+
+	require.Equal(t, exitOK, code, stderr.String())
+	assert.Empty(t, stdout.String())
+
+	written, err := os.ReadFile(output)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a", "b"}, strings.Fields(string(written)))
+}
+
+func TestRun_MissingInputFile(t *testing.T) {
+	t.Parallel()
+
+	var stdout, stderr bytes.Buffer
+
+	code := run(context.Background(), []string{
+		"-stage", prefixStage,
+		"-input", "in=" + filepath.Join(t.TempDir(), "missing.txt"),
+	}, strings.NewReader(""), &stdout, &stderr)
+
+	assert.Equal(t, exitFailure, code)
+	assert.Contains(t, stderr.String(), `input "in"`)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/topology"
+)
+
+const maxLineSize = 1 << 20
+
+type source struct {
+	channel string
+	reader  io.Reader
+}
+
+type sink struct {
+	channel string
+	writer  *lineWriter
+}
+
+// lineWriter lets several output channels write whole lines to the same file.
+type lineWriter struct {
+	mu     sync.Mutex
+	writer io.Writer
+}
+
+func (w *lineWriter) writeLine(line string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := io.WriteString(w.writer, line+"\n")
+
+	return err
+}
+
+func openStreams(
+	spec topology.Topology,
+	inputs, outputs bindingFlags,
+	stdin io.Reader,
+	stdout io.Writer,
+) ([]source, []sink, func() error, error) {
+	files := make([]*os.File, 0)
+
+	closeFiles := func() error {
+		errs := make([]error, 0)
+
+		for _, file := range files {
+			errs = append(errs, file.Close())
+		}
+
+		return errors.Join(errs...)
+	}
+
+	sources := make([]source, 0, len(spec.Inputs))
+
+	for _, channel := range spec.Inputs {
+		path := boundPath(inputs, channel)
+
+		if path == standardStream {
+			sources = append(sources, source{channel: channel, reader: stdin})
+
+			continue
+		}
+
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, nil, nil, errors.Join(fmt.Errorf("input %q: %w", channel, err), closeFiles())
+		}
+
+		files = append(files, file)
+		sources = append(sources, source{channel: channel, reader: file})
+	}
+
+	writers := map[string]*lineWriter{standardStream: {mu: sync.Mutex{}, writer: stdout}}
+	sinks := make([]sink, 0, len(spec.Outputs))
+
+	for _, channel := range spec.Outputs {
+		path := boundPath(outputs, channel)
+
+		if _, isOpen := writers[path]; !isOpen {
+			file, err := os.Create(path)
+			if err != nil {
+				return nil, nil, nil, errors.Join(fmt.Errorf("output %q: %w", channel, err), closeFiles())
+			}
+
+			files = append(files, file)
+			writers[path] = &lineWriter{mu: sync.Mutex{}, writer: file}
+		}
+
+		sinks = append(sinks, sink{channel: channel, writer: writers[path]})
+	}
+
+	return sources, sinks, closeFiles, nil
+}
+
+func boundPath(bindings bindingFlags, channel string) string {
+	for _, bound := range bindings {
+		if bound.channel == channel {
+			return bound.path
+		}
+	}
+
+	return standardStream
+}
+
+// execute runs the conveyer while feeding its inputs and writing its outputs.
+// The conveyer is drained once every input is exhausted; a failed input
+// cancels it instead.
+func execute(ctx context.Context, conv *conveyer.Conveyer, sources []source, sinks []sink) error {
+	runCtx, cancelRun := context.WithCancel(ctx)
+	defer cancelRun()
+
+	// Feeding and writing stop when Run returns, even if stdin is still open.
+	ioCtx, stopIO := context.WithCancel(context.Background())
+	defer stopIO()
+
+	runErr := make(chan error, 1)
+
+	go func() {
+		err := conv.Run(runCtx)
+
+		stopIO()
+		runErr <- err
+	}()
+
+	var feeders, writers errgroup.Group
+
+	for _, input := range sources {
+		feeders.Go(func() error {
+			return feed(ioCtx, conv, input)
+		})
+	}
+
+	for _, output := range sinks {
+		writers.Go(func() error {
+			return write(ioCtx, conv, output)
+		})
+	}
+
+	feedErr := make(chan error, 1)
+
+	go func() {
+		if err := feeders.Wait(); err != nil {
+			feedErr <- err
+
+			cancelRun()
+
+			return
+		}
+
+		conv.Drain()
+	}()
+
+	err := <-runErr
+	writeErr := writers.Wait()
+
+	select {
+	case err := <-feedErr:
+		return errors.Join(err, writeErr)
+	default:
+	}
+
+	if err != nil {
+		return errors.Join(err, writeErr)
+	}
+
+	return writeErr
+}
+
+func feed(ctx context.Context, conv *conveyer.Conveyer, input source) error {
+	scanner := bufio.NewScanner(input.reader)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxLineSize)
+
+	for scanner.Scan() {
+		if err := conv.SendContext(ctx, input.channel, scanner.Text()); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("input %q: %w", input.channel, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("input %q: %w", input.channel, err)
+	}
+
+	return nil
+}
+
+// write copies an output channel until it is closed. Once Run has returned the
+// messages still buffered in the channel are written as well.
+func write(ctx context.Context, conv *conveyer.Conveyer, output sink) error {
+	for {
+		line, err := conv.RecvContext(ctx, output.channel)
+
+		switch {
+		case err == nil:
+		case errors.Is(err, conveyer.ErrChannelClosed):
+			return nil
+		case ctx.Err() != nil:
+			return flush(conv, output)
+		default:
+			return fmt.Errorf("output %q: %w", output.channel, err)
+		}
+
+		if err := output.writer.writeLine(line); err != nil {
+			return fmt.Errorf("output %q: %w", output.channel, err)
+		}
+	}
+}
+
+func flush(conv *conveyer.Conveyer, output sink) error {
+	for {
+		line, err := conv.TryRecv(output.channel)
+		if errors.Is(err, conveyer.ErrNoData) || errors.Is(err, conveyer.ErrChannelClosed) {
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("output %q: %w", output.channel, err)
+		}
+
+		if err := output.writer.writeLine(line); err != nil {
+			return fmt.Errorf("output %q: %w", output.channel, err)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package conveyer_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+const benchBuffer = 128
+
+func passThrough(ctx context.Context, input, output chan string) error {
+	return handlers.Decorator(func(value string) (string, error) {
+		return value, nil
+	})(ctx, input, output)
+}
+
+func channelNames(prefix string, count int) []string {
+	names := make([]string, count)
+
+	for index := range names {
+		names[index] = fmt.Sprintf("%s%d", prefix, index)
+	}
+
+	return names
+}
+
+// benchmarkPipeline sends b.N messages spread over inputs and receives them
+// from outputs, reporting the throughput in messages per second.
+func benchmarkPipeline(b *testing.B, conv *conveyer.Conveyer, inputs, outputs []string) {
+	b.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- conv.Run(ctx)
+	}()
+
+	received := make(chan int, len(outputs))
+
+	for _, name := range outputs {
+		go func() {
+			count := 0
+
+			for {
+				if _, err := conv.Recv(name); err != nil {
+					received <- count
+
+					return
+				}
+
+				count++
+			}
+		}()
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for index := range b.N {
+		if err := conv.Send(inputs[index%len(inputs)], "message"); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	conv.Drain()
+
+	total := 0
+
+	for range outputs {
+		total += <-received
+	}
+
+	b.StopTimer()
+	b.ReportMetric(float64(total)/b.Elapsed().Seconds(), "msgs/s")
+
+	if err := <-errCh; err != nil {
+		b.Fatal(err)
+	}
+
+	if total != b.N {
+		b.Fatalf("received %d of %d messages", total, b.N)
+	}
+}
+
+func BenchmarkConveyer_SendRecv(b *testing.B) {
+	conv := conveyer.New(benchBuffer)
+	conv.DeclareInputs("in")
+
+	b.ReportAllocs()
+
+	for range b.N {
+		if err := conv.Send("in", "message"); err != nil {
+			b.Fatal(err)
+		}
+
+		if _, err := conv.Recv("in"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkConveyer_SingleStage(b *testing.B) {
+	conv := conveyer.New(benchBuffer)
+	if _, err := conv.RegisterDecorator(passThrough, "in", "out"); err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkPipeline(b, conv, []string{"in"}, []string{"out"})
+}
+
+func BenchmarkConveyer_FanOut(b *testing.B) {
+	outputs := channelNames("out", 4)
+
+	conv := conveyer.New(benchBuffer)
+	if _, err := conv.RegisterSeparator(handlers.SeparatorFunc, "in", outputs); err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkPipeline(b, conv, []string{"in"}, outputs)
+}
+
+func BenchmarkConveyer_FanIn(b *testing.B) {
+	inputs := channelNames("in", 4)
+
+	conv := conveyer.New(benchBuffer)
+	if _, err := conv.RegisterMultiplexer(handlers.MultiplexerFunc, inputs, "out"); err != nil {
+		b.Fatal(err)
+	}
+
+	benchmarkPipeline(b, conv, inputs, []string{"out"})
+}
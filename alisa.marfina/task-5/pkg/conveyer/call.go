@@ -0,0 +1,108 @@
+package conveyer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// CorrelationHeader carries the ID that Call matches replies by. Stages keep
+// headers of the messages they derive from, so it survives the pipeline.
+const CorrelationHeader = "Correlation-Id"
+
+// callRouter hands replies read by any waiting caller to the caller that owns
+// them, so callers sharing an output never steal each other's replies.
+type callRouter[T any] struct {
+	mu      sync.Mutex
+	pending map[string]chan Envelope[T]
+}
+
+func newCallRouter[T any]() *callRouter[T] {
+	return &callRouter[T]{
+		mu:      sync.Mutex{},
+		pending: make(map[string]chan Envelope[T]),
+	}
+}
+
+func (r *callRouter[T]) register(id string) chan Envelope[T] {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reply := make(chan Envelope[T], 1)
+	r.pending[id] = reply
+
+	return reply
+}
+
+func (r *callRouter[T]) forget(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.pending, id)
+}
+
+// deliver passes a reply to its caller. Replies nobody waits for, because the
+// caller gave up or already has one, are discarded.
+func (r *callRouter[T]) deliver(envelope Envelope[T]) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	reply, isPending := r.pending[envelope.Headers[CorrelationHeader]]
+	if !isPending {
+		return
+	}
+
+	select {
+	case reply <- envelope:
+	default:
+	}
+}
+
+// Call sends payload to the input channel and waits until the message derived
+// from it shows up on the output channel. Callers read the output while they
+// wait, so it should not be consumed with Recv at the same time; messages
+// without a waiting caller are discarded.
+func (c *Typed[T]) Call(ctx context.Context, inputName, outputName string, payload T) (T, error) {
+	var zero T
+
+	output, err := c.lookup(outputName)
+	if err != nil {
+		return zero, err
+	}
+
+	envelope := NewEnvelope(payload)
+	envelope.SetHeader(CorrelationHeader, envelope.ID)
+
+	reply := c.calls.register(envelope.ID)
+	defer c.calls.forget(envelope.ID)
+
+	if err := c.SendEnvelope(ctx, inputName, envelope); err != nil {
+		return zero, fmt.Errorf("call %q: %w", inputName, err)
+	}
+
+	for {
+		select {
+		case received := <-reply:
+			return received.Payload, nil
+
+		case received, isOpen := <-output:
+			if !isOpen {
+				select {
+				case received := <-reply:
+					return received.Payload, nil
+				default:
+					return zero, fmt.Errorf("call %q: %w", outputName, ErrChannelClosed)
+				}
+			}
+
+			if err := c.acknowledge(outputName, received.ID); err != nil {
+				return zero, fmt.Errorf("call %q: %w", outputName, err)
+			}
+
+			c.calls.deliver(received)
+
+		case <-ctx.Done():
+			return zero, fmt.Errorf("call %q: %w", inputName, ctx.Err())
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package conveyer_test
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func TestCall_ConcurrentCallersGetTheirReplies(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "decorated")
+	conv.RegisterSeparator(handlers.SeparatorFunc, "decorated", []string{"left", "right"})
+	conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"left", "right"}, "out")
+
+	runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	var waitGroup sync.WaitGroup
+
+	for index := range 20 {
+		waitGroup.Add(1)
+
+		go func() {
+			defer waitGroup.Done()
+
+			payload := "call " + strconv.Itoa(index)
+
+			reply, err := conv.Call(ctx, "in", "out", payload)
+			assert.NoError(t, err)
+			assert.Equal(t, "decorated: "+payload, reply)
+		}()
+	}
+
+	waitGroup.Wait()
+}
+
+func TestCall_AbandonedReplyIsDiscarded(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+	gated := handlers.Map(func(value string) string {
+		if value == "slow" {
+			<-release
+		}
+
+		return value
+	})
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(gated, "in", "out")
+
+	runInBackground(t, conv)
+
+	short, cancelShort := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelShort()
+
+	_, err := conv.Call(short, "in", "out", "slow")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	close(release)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	reply, err := conv.Call(ctx, "in", "out", "fast")
+	require.NoError(t, err)
+	assert.Equal(t, "fast", reply)
+
+	_, err = conv.TryRecv("out")
+	require.ErrorIs(t, err, conveyer.ErrNoData)
+}
+
+func TestCall_Errors(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	_, err := conv.Call(context.Background(), "in", "missing", "a")
+	require.ErrorIs(t, err, conveyer.ErrChanNotFound)
+
+	_, err = conv.Call(context.Background(), "missing", "out", "a")
+	require.ErrorIs(t, err, conveyer.ErrChanNotFound)
+
+	_, errCh := runInBackground(t, conv)
+
+	conv.Drain()
+	require.NoError(t, <-errCh)
+
+	_, err = conv.Call(context.Background(), "in", "out", "a")
+	require.ErrorIs(t, err, conveyer.ErrDraining)
+}
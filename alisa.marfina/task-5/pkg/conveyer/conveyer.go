@@ -5,146 +5,424 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"golang.org/x/sync/errgroup"
 )
 
-var ErrChanNotFound = errors.New("chan not found")
+var (
+	ErrChanNotFound  = errors.New("chan not found")
+	ErrChanFull      = errors.New("chan is full")
+	ErrNoData        = errors.New("no data in chan")
+	ErrChannelClosed = errors.New("chan is closed")
+)
+
+type stageKind string
+
+const (
+	kindDecorator   stageKind = "decorator"
+	kindMultiplexer stageKind = "multiplexer"
+	kindSeparator   stageKind = "separator"
+)
 
-type Conveyer struct {
-	mu        sync.RWMutex
-	streams   map[string]chan string
-	processes []func(ctx context.Context) error
-	bufSize   int
+type stage[T any] struct {
+	id      int
+	name    string
+	kind    stageKind
+	inputs  []string
+	outputs []string
+	invoke  func(ctx context.Context, inputs []chan T, outputs []chan T) error
+	config  stageConfig
+	metrics *stageMetrics
+	control *stageControl
 }
 
-func New(size int) *Conveyer {
-	return &Conveyer{
-		mu:        sync.RWMutex{},
-		streams:   make(map[string]chan string),
-		processes: make([]func(ctx context.Context) error, 0),
-		bufSize:   size,
+type options struct {
+	metrics  bool
+	durable  durableOptions
+	channels map[string][]ChannelOption
+	spillDir string
+	tracing  SpanExporter
+}
+
+type Option func(*options)
+
+func WithMetrics() Option {
+	return func(opts *options) {
+		opts.metrics = true
 	}
 }
 
-func (c *Conveyer) ensureChan(name string) chan string {
+// Typed is a conveyer whose streams carry values of type T.
+type Typed[T any] struct {
+	mu          sync.RWMutex
+	streams     map[string]chan Envelope[T]
+	states      map[string]*channelState[T]
+	table       atomic.Pointer[channelTable[T]]
+	stages      []stage[T]
+	nextStageID int
+	deadLetters map[string]chan DeadLetter[T]
+	sources     map[string]struct{}
+	sinks       map[string]struct{}
+	bufSize     int
+	options     options
+	durable     map[string]*durableLog[T]
+	durableErrs []error
+	handoff     map[string][]Envelope[T]
+	calls       *callRouter[T]
+	state       atomic.Int32
+
+	draining  atomic.Pointer[chan struct{}]
+	drainOnce sync.Once
+	runCancel context.CancelFunc
+	runDone   <-chan struct{}
+	run       *runState
+}
+
+// Conveyer is the string conveyer that New returns.
+type Conveyer = Typed[string]
+
+func New(size int, opts ...Option) *Conveyer {
+	return NewTyped[string](size, opts...)
+}
+
+func NewTyped[T any](size int, opts ...Option) *Typed[T] {
+	var conveyerOptions options
+
+	for _, opt := range opts {
+		opt(&conveyerOptions)
+	}
+
+	conv := &Typed[T]{
+		mu:          sync.RWMutex{},
+		streams:     make(map[string]chan Envelope[T]),
+		states:      make(map[string]*channelState[T]),
+		table:       atomic.Pointer[channelTable[T]]{},
+		stages:      make([]stage[T], 0),
+		nextStageID: 0,
+		deadLetters: make(map[string]chan DeadLetter[T]),
+		sources:     make(map[string]struct{}),
+		sinks:       make(map[string]struct{}),
+		bufSize:     size,
+		options:     conveyerOptions,
+		durable:     make(map[string]*durableLog[T]),
+		durableErrs: nil,
+		handoff:     make(map[string][]Envelope[T]),
+		calls:       newCallRouter[T](),
+		state:       atomic.Int32{},
+		draining:    atomic.Pointer[chan struct{}]{},
+		drainOnce:   sync.Once{},
+		runCancel:   nil,
+		runDone:     nil,
+		run:         nil,
+	}
+
+	draining := make(chan struct{})
+	conv.draining.Store(&draining)
+	conv.publishLocked()
+
+	return conv
+}
+
+func (c *Typed[T]) ensureChan(name string) chan Envelope[T] {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
+	return c.ensureChanLocked(name)
+}
+
+func (c *Typed[T]) ensureChanLocked(name string) chan Envelope[T] {
 	if channel, exists := c.streams[name]; exists {
 		return channel
 	}
 
-	channel := make(chan string, c.bufSize)
+	replay := c.openDurable(name)
+	config := c.channelConfig(name)
+
+	channel := make(chan Envelope[T], max(config.capacity, len(replay)))
 	c.streams[name] = channel
+	c.states[name] = newChannelState[T](config)
+
+	for _, envelope := range replay {
+		channel <- envelope
+	}
+
+	c.publishLocked()
 
 	return channel
 }
 
-func (c *Conveyer) RegisterDecorator(
-	callback func(context.Context, chan string, chan string) error,
-	inputName string,
-	outputName string,
-) {
-	c.ensureChan(inputName)
-	c.ensureChan(outputName)
+func (c *Typed[T]) addStage(
+	kind stageKind,
+	inputs []string,
+	outputs []string,
+	invoke func(ctx context.Context, inputs []chan T, outputs []chan T) error,
+	opts []StageOption,
+) (*StageHandle[T], error) {
+	if err := c.admitStage(); err != nil {
+		return nil, err
+	}
 
-	c.processes = append(c.processes, func(ctx context.Context) error {
-		input := c.ensureChan(inputName)
-		output := c.ensureChan(outputName)
+	config := newStageConfig(opts)
 
-		return callback(ctx, input, output)
-	})
-}
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-func (c *Conveyer) RegisterMultiplexer(
-	callback func(context.Context, []chan string, chan string) error,
-	inputNames []string,
-	outputName string,
-) {
-	for _, name := range inputNames {
-		c.ensureChan(name)
-	}
+	id := c.nextStageID
 
-	c.ensureChan(outputName)
+	name := config.name
+	if name == "" {
+		name = fmt.Sprintf("%s_%d", kind, id)
+	}
 
-	c.processes = append(c.processes, func(ctx context.Context) error {
-		inputs := make([]chan string, len(inputNames))
+	registered := stage[T]{
+		id:      id,
+		name:    name,
+		kind:    kind,
+		inputs:  append([]string(nil), inputs...),
+		outputs: append([]string(nil), outputs...),
+		invoke:  invoke,
+		config:  config,
+		metrics: newStageMetrics(),
+		control: newStageControl(),
+	}
 
-		for index, name := range inputNames {
-			inputs[index] = c.ensureChan(name)
+	// Run validates the graph once, so a stage joining a running conveyer is
+	// checked against the stages that are already running.
+	if c.run != nil {
+		if err := c.checkAddedLocked(registered); err != nil {
+			return nil, err
 		}
+	}
 
-		return callback(ctx, inputs, c.ensureChan(outputName))
-	})
+	c.nextStageID++
+
+	for _, name := range inputs {
+		c.ensureChanLocked(name)
+	}
+
+	for _, name := range outputs {
+		c.ensureChanLocked(name)
+	}
+
+	if config.policy.Kind == PolicyDeadLetter {
+		c.ensureDeadLetterLocked(config.policy.DeadLetterChannel)
+	}
+
+	c.stages = append(c.stages, registered)
+
+	if c.run != nil {
+		c.startLocked(registered)
+	}
+
+	return &StageHandle[T]{conveyer: c, id: id, name: name}, nil
+}
+
+func (c *Typed[T]) RegisterDecorator(
+	callback func(context.Context, chan T, chan T) error,
+	inputName string,
+	outputName string,
+	opts ...StageOption,
+) (*StageHandle[T], error) {
+	return c.addStage(kindDecorator, []string{inputName}, []string{outputName},
+		func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+			return callback(ctx, inputs[0], outputs[0])
+		}, opts)
+}
+
+func (c *Typed[T]) RegisterMultiplexer(
+	callback func(context.Context, []chan T, chan T) error,
+	inputNames []string,
+	outputName string,
+	opts ...StageOption,
+) (*StageHandle[T], error) {
+	return c.addStage(kindMultiplexer, inputNames, []string{outputName},
+		func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+			return callback(ctx, inputs, outputs[0])
+		}, opts)
 }
 
-func (c *Conveyer) RegisterSeparator(
-	callback func(context.Context, chan string, []chan string) error,
+func (c *Typed[T]) RegisterSeparator(
+	callback func(context.Context, chan T, []chan T) error,
 	inputName string,
 	outputNames []string,
-) {
-	c.ensureChan(inputName)
+	opts ...StageOption,
+) (*StageHandle[T], error) {
+	return c.addStage(kindSeparator, []string{inputName}, outputNames,
+		func(ctx context.Context, inputs []chan T, outputs []chan T) error {
+			return callback(ctx, inputs[0], outputs)
+		}, opts)
+}
 
-	for _, name := range outputNames {
-		c.ensureChan(name)
+func (c *Typed[T]) lookup(pipeName string) (chan Envelope[T], error) {
+	channel, exists := c.channels().streams[pipeName]
+	if !exists {
+		return nil, ErrChanNotFound
 	}
 
-	c.processes = append(c.processes, func(ctx context.Context) error {
-		outputs := make([]chan string, len(outputNames))
+	return channel, nil
+}
 
-		for index, name := range outputNames {
-			outputs[index] = c.ensureChan(name)
-		}
+func (c *Typed[T]) Send(pipeName string, data T) error {
+	return c.SendContext(context.Background(), pipeName, data)
+}
 
-		return callback(ctx, c.ensureChan(inputName), outputs)
-	})
+func (c *Typed[T]) SendContext(ctx context.Context, pipeName string, data T) error {
+	return c.SendEnvelope(ctx, pipeName, NewEnvelope(data))
 }
 
-func (c *Conveyer) Send(pipeName string, data string) error {
-	c.mu.RLock()
-	channel, exists := c.streams[pipeName]
-	c.mu.RUnlock()
+func (c *Typed[T]) SendEnvelope(ctx context.Context, pipeName string, envelope Envelope[T]) error {
+	envelope = envelope.complete()
 
-	if !exists {
-		return ErrChanNotFound
+	channel, state, err := c.enterSend(pipeName)
+	if err != nil {
+		return err
 	}
+	defer state.leave()
 
-	channel <- data
+	if err := c.persist(pipeName, envelope); err != nil {
+		return err
+	}
+
+	if err := c.push(ctx, pipeName, channel, envelope, c.drainSignal()); err != nil {
+		return errors.Join(err, c.acknowledge(pipeName, envelope.ID))
+	}
 
 	return nil
 }
 
-func (c *Conveyer) Recv(pipeName string) (string, error) {
-	c.mu.RLock()
-	channel, exists := c.streams[pipeName]
-	c.mu.RUnlock()
+func (c *Typed[T]) TrySend(pipeName string, data T) error {
+	channel, state, err := c.enterSend(pipeName)
+	if err != nil {
+		return err
+	}
+	defer state.leave()
 
-	if !exists {
-		return "", ErrChanNotFound
+	envelope := NewEnvelope(data)
+
+	if err := c.persist(pipeName, envelope); err != nil {
+		return err
 	}
 
-	value, isOpen := <-channel
+	if state.overflow != OverflowBlock {
+		return c.push(context.Background(), pipeName, channel, envelope, nil)
+	}
 
-	if !isOpen {
-		return "undefined", nil
+	select {
+	case channel <- envelope:
+		return nil
+	default:
+		return errors.Join(ErrChanFull, c.acknowledge(pipeName, envelope.ID))
 	}
+}
+
+func (c *Typed[T]) Recv(pipeName string) (T, error) {
+	return c.RecvContext(context.Background(), pipeName)
+}
+
+func (c *Typed[T]) RecvContext(ctx context.Context, pipeName string) (T, error) {
+	envelope, err := c.RecvEnvelope(ctx, pipeName)
 
-	return value, nil
+	return envelope.Payload, err
 }
 
-func (c *Conveyer) Run(ctx context.Context) error {
-	errorGroup, groupCtx := errgroup.WithContext(ctx)
+func (c *Typed[T]) RecvEnvelope(ctx context.Context, pipeName string) (Envelope[T], error) {
+	channel, err := c.lookup(pipeName)
+	if err != nil {
+		return Envelope[T]{}, err
+	}
+
+	select {
+	case envelope, isOpen := <-channel:
+		if !isOpen {
+			return Envelope[T]{}, ErrChannelClosed
+		}
+
+		return envelope, c.acknowledge(pipeName, envelope.ID)
+
+	case <-ctx.Done():
+		return Envelope[T]{}, fmt.Errorf("recv from %q: %w", pipeName, ctx.Err())
+	}
+}
 
-	for _, processor := range c.processes {
-		processorCopy := processor
+func (c *Typed[T]) TryRecv(pipeName string) (T, error) {
+	var zero T
 
-		errorGroup.Go(func() error {
-			return processorCopy(groupCtx)
-		})
+	channel, err := c.lookup(pipeName)
+	if err != nil {
+		return zero, err
 	}
 
-	if err := errorGroup.Wait(); err != nil {
+	select {
+	case envelope, isOpen := <-channel:
+		if !isOpen {
+			return zero, ErrChannelClosed
+		}
+
+		return envelope.Payload, c.acknowledge(pipeName, envelope.ID)
+
+	default:
+		return zero, ErrNoData
+	}
+}
+
+func (c *Typed[T]) Run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+
+	defer close(done)
+	defer cancel()
+
+	c.mu.Lock()
+	err := c.beginRunLocked(cancel, done)
+	c.mu.Unlock()
+
+	if err != nil {
+		return fmt.Errorf("conveyer run error: %w", err)
+	}
+
+	if err := c.Validate(); err != nil {
+		c.mu.Lock()
+		c.setState(StateConfiguring)
+		c.mu.Unlock()
+
+		return fmt.Errorf("conveyer run error: %w", err)
+	}
+
+	errorGroup, groupCtx := errgroup.WithContext(runCtx)
+	run := newRunState(groupCtx, errorGroup)
+
+	defer c.closeDeadLetters()
+
+	// The run waits on idle as well as on its stages, so stages added while it
+	// is running are never started on a group that has already been waited out.
+	errorGroup.Go(func() error {
+		select {
+		case <-run.idle:
+		case <-groupCtx.Done():
+		}
+
+		return nil
+	})
+
+	c.mu.Lock()
+	c.run = run
+
+	for _, registered := range c.stages {
+		c.startLocked(registered)
+	}
+
+	c.checkIdleLocked()
+	c.mu.Unlock()
+
+	err = errorGroup.Wait()
+
+	c.mu.Lock()
+	c.run = nil
+	c.setState(StateStopped)
+	c.mu.Unlock()
+
+	if err != nil {
 		return fmt.Errorf("conveyer run error: %w", err)
 	}
 
@@ -0,0 +1,200 @@
+package conveyer_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+type order struct {
+	ID    int
+	Price int
+}
+
+func TestConveyer_StringPipeline(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "decorated")
+	require.NoError(t, err)
+	_, err = conv.RegisterSeparator(handlers.SeparatorFunc, "decorated", []string{"left", "right"})
+	require.NoError(t, err)
+	_, err = conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"left", "right"}, "out")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- conv.Run(ctx)
+	}()
+
+	for i := range 4 {
+		require.NoError(t, conv.Send("in", "msg "+strconv.Itoa(i)))
+	}
+
+	received := make([]string, 0, 4)
+
+	for range 4 {
+		value, err := conv.Recv("out")
+		require.NoError(t, err)
+
+		received = append(received, value)
+	}
+
+	assert.ElementsMatch(t, []string{
+		"decorated: msg 0", "decorated: msg 1", "decorated: msg 2", "decorated: msg 3",
+	}, received)
+
+	_, err = conv.Recv("missing")
+	require.ErrorIs(t, err, conveyer.ErrChanNotFound)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+// pipelineOwner names the conveyer type the way code written before it became
+// generic does.
+type pipelineOwner struct {
+	conv *conveyer.Conveyer
+}
+
+func TestConveyer_UntypedName(t *testing.T) {
+	t.Parallel()
+
+	owner := pipelineOwner{conv: conveyer.New(1)}
+	_, err := owner.conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- owner.conv.Run(ctx)
+	}()
+
+	require.NoError(t, owner.conv.Send("in", "msg"))
+
+	value, err := owner.conv.Recv("out")
+	require.NoError(t, err)
+	assert.Equal(t, "decorated: msg", value)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestConveyer_Typed(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.NewTyped[order](5)
+	_, err := conv.RegisterDecorator(handlers.Decorator(func(value order) (order, error) {
+		value.Price *= 2
+
+		return value, nil
+	}), "orders", "priced")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- conv.Run(ctx)
+	}()
+
+	require.NoError(t, conv.Send("orders", order{ID: 1, Price: 21}))
+
+	value, err := conv.Recv("priced")
+	require.NoError(t, err)
+	assert.Equal(t, order{ID: 1, Price: 42}, value)
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestConveyer_DecoratorError(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	require.NoError(t, conv.Send("in", "no decorator"))
+
+	err = conv.Run(context.Background())
+	require.ErrorIs(t, err, handlers.ErrCannotBeDecorated)
+
+	_, err = conv.Recv("out")
+	require.ErrorIs(t, err, conveyer.ErrChannelClosed)
+}
+
+func TestConveyer_ContextAndNonBlocking(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	_, err = conv.TryRecv("in")
+	require.ErrorIs(t, err, conveyer.ErrNoData)
+
+	require.NoError(t, conv.TrySend("in", "first"))
+	require.ErrorIs(t, conv.TrySend("in", "second"), conveyer.ErrChanFull)
+	require.ErrorIs(t, conv.TrySend("missing", "x"), conveyer.ErrChanNotFound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	err = conv.SendContext(ctx, "in", "second")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	_, err = conv.RecvContext(ctx, "out")
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+
+	value, err := conv.TryRecv("in")
+	require.NoError(t, err)
+	assert.Equal(t, "first", value)
+}
+
+func TestConveyer_Stream(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(5)
+	_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	for _, value := range []string{"a", "undefined", "c"} {
+		require.NoError(t, conv.Send("in", value))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	require.NoError(t, conv.Run(ctx))
+
+	received := make([]string, 0, 3)
+
+	for value, err := range conv.Stream("out") {
+		require.NoError(t, err)
+
+		received = append(received, value)
+	}
+
+	assert.Equal(t, []string{"decorated: a", "decorated: undefined", "decorated: c"}, received)
+
+	for _, err := range conv.Stream("missing") {
+		require.ErrorIs(t, err, conveyer.ErrChanNotFound)
+	}
+}
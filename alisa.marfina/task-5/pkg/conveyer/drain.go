@@ -0,0 +1,127 @@
+package conveyer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrDraining     = errors.New("conveyer is draining")
+	ErrDrainTimeout = errors.New("conveyer drain timed out")
+)
+
+// drainSignal is closed by Drain. Reset replaces it, hence the atomic pointer.
+func (c *Typed[T]) drainSignal() chan struct{} {
+	return *c.draining.Load()
+}
+
+func (c *Typed[T]) isDraining() bool {
+	select {
+	case <-c.drainSignal():
+		return true
+	default:
+		return false
+	}
+}
+
+// Drain closes every source channel, i.e. every channel no stage writes to, so
+// stages finish the buffered messages and close their outputs in turn. Sends
+// started before Drain are released with ErrDraining.
+func (c *Typed[T]) Drain() {
+	c.drainOnce.Do(func() {
+		close(c.drainSignal())
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		if c.State() == StateRunning {
+			c.setState(StateDraining)
+		}
+
+		for _, name := range c.sourceNames() {
+			c.closeStreamLocked(name)
+		}
+
+		c.checkIdleLocked()
+	})
+}
+
+// Stop drains the conveyer and waits for Run to return. When ctx expires first
+// the run is cancelled and the number of messages left in channels that some stage
+// was still supposed to read is reported as lost.
+func (c *Typed[T]) Stop(ctx context.Context) (int, error) {
+	c.Drain()
+
+	c.mu.RLock()
+	done, cancel := c.runDone, c.runCancel
+	c.mu.RUnlock()
+
+	if done == nil {
+		return 0, nil
+	}
+
+	select {
+	case <-done:
+		return 0, nil
+	case <-ctx.Done():
+	}
+
+	cancel()
+	<-done
+
+	lost := c.unprocessed()
+
+	return lost, fmt.Errorf("%w: %d messages lost", ErrDrainTimeout, lost)
+}
+
+func (c *Typed[T]) sourceNames() []string {
+	written := make(map[string]bool)
+
+	for _, registered := range c.stages {
+		for _, name := range registered.outputs {
+			written[name] = true
+		}
+	}
+
+	sources := make([]string, 0)
+
+	for _, name := range sortedKeys(c.streams) {
+		if !written[name] {
+			sources = append(sources, name)
+		}
+	}
+
+	return sources
+}
+
+func (c *Typed[T]) unprocessed() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	read := make(map[string]bool)
+
+	for _, registered := range c.stages {
+		for _, name := range registered.inputs {
+			read[name] = true
+		}
+	}
+
+	lost := 0
+
+	for _, registered := range c.stages {
+		lost += int(registered.metrics.stranded.Load())
+	}
+
+	for name, channel := range c.streams {
+		if read[name] {
+			lost += len(channel) + c.states[name].spilled()
+		}
+	}
+
+	for _, envelopes := range c.handoff {
+		lost += len(envelopes)
+	}
+
+	return lost
+}
@@ -0,0 +1,140 @@
+package conveyer_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func TestConveyer_StopDrainsInFlight(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "mid")
+	conv.RegisterSeparator(handlers.SeparatorFunc, "mid", []string{"out"})
+
+	_, errCh := runInBackground(t, conv)
+
+	for _, value := range []string{"a", "b", "c"} {
+		require.NoError(t, conv.Send("in", value))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	lost, err := conv.Stop(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, lost)
+	require.NoError(t, <-errCh)
+
+	require.ErrorIs(t, conv.Send("in", "late"), conveyer.ErrDraining)
+
+	received := make([]string, 0, 3)
+
+	for value, err := range conv.Stream("out") {
+		require.NoError(t, err)
+
+		received = append(received, value)
+	}
+
+	assert.Equal(t, []string{"decorated: a", "decorated: b", "decorated: c"}, received)
+}
+
+func TestConveyer_StopTimeoutReportsLost(t *testing.T) {
+	t.Parallel()
+
+	stuck := func(ctx context.Context, input, output chan string) error {
+		defer close(output)
+
+		<-input
+		<-ctx.Done()
+
+		return nil
+	}
+
+	conv := conveyer.New(3)
+	conv.RegisterDecorator(stuck, "in", "out")
+
+	runInBackground(t, conv)
+
+	for _, value := range []string{"a", "b", "c"} {
+		require.NoError(t, conv.Send("in", value))
+	}
+
+	require.Eventually(t, func() bool {
+		return conv.Stats().Channels[0].Depth == 1
+	}, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	lost, err := conv.Stop(ctx)
+	require.ErrorIs(t, err, conveyer.ErrDrainTimeout)
+	assert.Equal(t, 2, lost)
+}
+
+func TestConveyer_DrainRacesSenders(t *testing.T) {
+	t.Parallel()
+
+	const senders = 8
+
+	conv := conveyer.New(1)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	_, errCh := runInBackground(t, conv)
+
+	var (
+		group    sync.WaitGroup
+		accepted atomic.Int64
+	)
+
+	for range senders {
+		group.Add(1)
+
+		go func() {
+			defer group.Done()
+
+			for {
+				err := conv.Send("in", "message")
+				if err != nil {
+					assert.ErrorIs(t, err, conveyer.ErrDraining)
+
+					return
+				}
+
+				accepted.Add(1)
+			}
+		}()
+	}
+
+	received := make(chan int, 1)
+
+	go func() {
+		count := 0
+
+		for _, err := range conv.Stream("out") {
+			assert.NoError(t, err)
+
+			count++
+		}
+
+		received <- count
+	}()
+
+	require.Eventually(t, func() bool {
+		return accepted.Load() > 100
+	}, time.Second, time.Millisecond)
+
+	conv.Drain()
+	group.Wait()
+	require.NoError(t, <-errCh)
+	assert.Equal(t, int(accepted.Load()), <-received)
+}
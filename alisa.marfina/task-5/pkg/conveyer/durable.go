@@ -0,0 +1,491 @@
+package conveyer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+var ErrCorruptLog = errors.New("durable log is corrupt")
+
+const (
+	defaultSegmentSize = 4 << 20
+	segmentSuffix      = ".log"
+	segmentNameFormat  = "%020d" + segmentSuffix
+	logDirMode         = 0o750
+	logFileMode        = 0o640
+	maxRecordSize      = 64 << 20
+)
+
+type durableOptions struct {
+	dir         string
+	channels    map[string]struct{}
+	segmentSize int64
+}
+
+// WithDurableChannels backs the named channels with an append-only log under
+// dir. Messages are appended before they enter the channel and acknowledged once
+// the reading stage has moved past them, so whatever was unacknowledged when the
+// process stopped is replayed into the channel by the next conveyer.
+func WithDurableChannels(dir string, names ...string) Option {
+	return func(opts *options) {
+		opts.durable.dir = dir
+
+		if opts.durable.channels == nil {
+			opts.durable.channels = make(map[string]struct{})
+		}
+
+		for _, name := range names {
+			opts.durable.channels[name] = struct{}{}
+		}
+	}
+}
+
+// WithSegmentSize sets the size at which a durable log starts a new segment.
+// Older segments are deleted as soon as every message in them is acknowledged.
+func WithSegmentSize(bytes int64) Option {
+	return func(opts *options) {
+		opts.durable.segmentSize = bytes
+	}
+}
+
+// logRecord is keyed by a per-log sequence number rather than the envelope ID
+// because a handler may write several outputs derived from the same message.
+type logRecord[T any] struct {
+	Seq uint64       `json:"seq"`
+	Put *Envelope[T] `json:"put,omitempty"`
+	Ack bool         `json:"ack,omitempty"`
+}
+
+type entry[T any] struct {
+	seq      uint64
+	envelope Envelope[T]
+}
+
+type segment struct {
+	index   uint64
+	path    string
+	pending int
+}
+
+type durableLog[T any] struct {
+	mu          sync.Mutex
+	dir         string
+	segmentSize int64
+	segments    []*segment
+	active      *os.File
+	activeSize  int64
+	nextSeq     uint64
+	unacked     map[string][]uint64
+	owners      map[uint64]*segment
+}
+
+// openDurableLog replays the log in dir and compacts it into a single segment
+// holding only the unacknowledged messages, which are returned in append order.
+func openDurableLog[T any](dir string, segmentSize int64) (*durableLog[T], []Envelope[T], error) {
+	if segmentSize <= 0 {
+		segmentSize = defaultSegmentSize
+	}
+
+	if err := os.MkdirAll(dir, logDirMode); err != nil {
+		return nil, nil, fmt.Errorf("create log dir: %w", err)
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+segmentSuffix))
+	if err != nil {
+		return nil, nil, fmt.Errorf("list segments: %w", err)
+	}
+
+	slices.Sort(paths)
+
+	entries, err := replaySegments[T](paths)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log := &durableLog[T]{
+		mu:          sync.Mutex{},
+		dir:         dir,
+		segmentSize: segmentSize,
+		segments:    make([]*segment, 0),
+		active:      nil,
+		activeSize:  0,
+		nextSeq:     1,
+		unacked:     make(map[string][]uint64),
+		owners:      make(map[uint64]*segment),
+	}
+
+	var last uint64
+
+	if len(paths) > 0 {
+		_, _ = fmt.Sscanf(filepath.Base(paths[len(paths)-1]), segmentNameFormat, &last)
+	}
+
+	if err := log.compact(last+1, entries, paths); err != nil {
+		return nil, nil, err
+	}
+
+	envelopes := make([]Envelope[T], len(entries))
+
+	for index, replayed := range entries {
+		envelopes[index] = replayed.envelope
+	}
+
+	return log, envelopes, nil
+}
+
+func replaySegments[T any](paths []string) ([]entry[T], error) {
+	puts := make(map[uint64]Envelope[T])
+
+	for _, path := range paths {
+		records, err := readSegment[T](path)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range records {
+			switch {
+			case record.Ack:
+				delete(puts, record.Seq)
+			case record.Put != nil:
+				puts[record.Seq] = *record.Put
+			}
+		}
+	}
+
+	entries := make([]entry[T], 0, len(puts))
+
+	for _, seq := range slices.Sorted(maps.Keys(puts)) {
+		entries = append(entries, entry[T]{seq: seq, envelope: puts[seq]})
+	}
+
+	return entries, nil
+}
+
+// readSegment tolerates a torn last record, which is what a crash in the middle
+// of an append leaves behind.
+func readSegment[T any](path string) ([]logRecord[T], error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open segment: %w", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), maxRecordSize)
+
+	records := make([]logRecord[T], 0)
+
+	var broken error
+
+	for scanner.Scan() {
+		if broken != nil {
+			return nil, broken
+		}
+
+		var record logRecord[T]
+
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			broken = fmt.Errorf("%w: %s: %w", ErrCorruptLog, filepath.Base(path), err)
+
+			continue
+		}
+
+		records = append(records, record)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read segment: %w", err)
+	}
+
+	return records, nil
+}
+
+// compact keeps the sequence numbers of the surviving messages, so a crash
+// between installing the new segment and removing the old ones only leaves
+// duplicates that the next replay collapses.
+func (l *durableLog[T]) compact(index uint64, entries []entry[T], stale []string) error {
+	final := filepath.Join(l.dir, fmt.Sprintf(segmentNameFormat, index))
+	temporary := final + ".tmp"
+
+	var buffer bytes.Buffer
+
+	for _, kept := range entries {
+		line, err := json.Marshal(logRecord[T]{Seq: kept.seq, Put: &kept.envelope, Ack: false})
+		if err != nil {
+			return fmt.Errorf("encode message: %w", err)
+		}
+
+		buffer.Write(append(line, '\n'))
+	}
+
+	if err := writeSynced(temporary, buffer.Bytes()); err != nil {
+		return err
+	}
+
+	if err := os.Rename(temporary, final); err != nil {
+		return fmt.Errorf("install compacted segment: %w", err)
+	}
+
+	for _, path := range stale {
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("remove segment: %w", err)
+		}
+	}
+
+	active, err := os.OpenFile(final, os.O_WRONLY|os.O_APPEND, logFileMode)
+	if err != nil {
+		return fmt.Errorf("open segment: %w", err)
+	}
+
+	current := &segment{index: index, path: final, pending: len(entries)}
+	l.segments = append(l.segments, current)
+	l.active, l.activeSize = active, int64(buffer.Len())
+
+	for _, kept := range entries {
+		l.track(kept.seq, kept.envelope.ID, current)
+		l.nextSeq = kept.seq + 1
+	}
+
+	return nil
+}
+
+func writeSynced(path string, data []byte) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, logFileMode)
+	if err != nil {
+		return fmt.Errorf("create segment: %w", err)
+	}
+
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("write segment: %w", err)
+	}
+
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+
+		return fmt.Errorf("sync segment: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("close segment: %w", err)
+	}
+
+	return nil
+}
+
+func (l *durableLog[T]) append(envelope Envelope[T]) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seq := l.nextSeq
+	current := l.segments[len(l.segments)-1]
+
+	if err := l.write(logRecord[T]{Seq: seq, Put: &envelope, Ack: false}); err != nil {
+		return err
+	}
+
+	l.nextSeq++
+	current.pending++
+	l.track(seq, envelope.ID, current)
+
+	return nil
+}
+
+func (l *durableLog[T]) track(seq uint64, id string, owner *segment) {
+	l.unacked[id] = append(l.unacked[id], seq)
+	l.owners[seq] = owner
+}
+
+func (l *durableLog[T]) ack(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	seqs := l.unacked[id]
+	if len(seqs) == 0 {
+		return nil
+	}
+
+	seq := seqs[0]
+
+	if err := l.write(logRecord[T]{Seq: seq, Put: nil, Ack: true}); err != nil {
+		return err
+	}
+
+	if len(seqs) == 1 {
+		delete(l.unacked, id)
+	} else {
+		l.unacked[id] = seqs[1:]
+	}
+
+	l.owners[seq].pending--
+	delete(l.owners, seq)
+
+	// Only a prefix of fully acknowledged segments can go: a later segment may
+	// hold the acks for messages that an earlier one still has to replay.
+	for len(l.segments) > 1 && l.segments[0].pending == 0 {
+		if err := os.Remove(l.segments[0].path); err != nil {
+			return fmt.Errorf("remove segment: %w", err)
+		}
+
+		l.segments = l.segments[1:]
+	}
+
+	return nil
+}
+
+func (l *durableLog[T]) write(record logRecord[T]) error {
+	if l.active == nil {
+		return fmt.Errorf("write record: %w", os.ErrClosed)
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	written, err := l.active.Write(append(line, '\n'))
+	l.activeSize += int64(written)
+
+	if err != nil {
+		return fmt.Errorf("write record: %w", err)
+	}
+
+	if l.activeSize >= l.segmentSize {
+		return l.rotate()
+	}
+
+	return nil
+}
+
+func (l *durableLog[T]) rotate() error {
+	index := l.segments[len(l.segments)-1].index + 1
+	path := filepath.Join(l.dir, fmt.Sprintf(segmentNameFormat, index))
+
+	next, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, logFileMode)
+	if err != nil {
+		return fmt.Errorf("create segment: %w", err)
+	}
+
+	if err := l.active.Close(); err != nil {
+		_ = next.Close()
+
+		return fmt.Errorf("close segment: %w", err)
+	}
+
+	l.segments = append(l.segments, &segment{index: index, path: path, pending: 0})
+	l.active, l.activeSize = next, 0
+
+	return nil
+}
+
+func (l *durableLog[T]) close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.active == nil {
+		return nil
+	}
+
+	active := l.active
+	l.active = nil
+
+	if err := active.Sync(); err != nil {
+		_ = active.Close()
+
+		return fmt.Errorf("sync segment: %w", err)
+	}
+
+	if err := active.Close(); err != nil {
+		return fmt.Errorf("close segment: %w", err)
+	}
+
+	return nil
+}
+
+// openDurable is called with c.mu held while the channel is being created.
+func (c *Typed[T]) openDurable(name string) []Envelope[T] {
+	if _, isDurable := c.options.durable.channels[name]; !isDurable {
+		return nil
+	}
+
+	dir := filepath.Join(c.options.durable.dir, url.PathEscape(name))
+
+	log, unacked, err := openDurableLog[T](dir, c.options.durable.segmentSize)
+	if err != nil {
+		c.durableErrs = append(c.durableErrs, fmt.Errorf("durable channel %q: %w", name, err))
+
+		return nil
+	}
+
+	c.durable[name] = log
+
+	return unacked
+}
+
+func (c *Typed[T]) durableLogFor(name string) *durableLog[T] {
+	return c.channels().durable[name]
+}
+
+func (c *Typed[T]) persist(name string, envelope Envelope[T]) error {
+	log := c.durableLogFor(name)
+	if log == nil {
+		return nil
+	}
+
+	if err := log.append(envelope); err != nil {
+		return fmt.Errorf("durable channel %q: %w", name, err)
+	}
+
+	return nil
+}
+
+func (c *Typed[T]) acknowledge(name string, id string) error {
+	log := c.durableLogFor(name)
+	if log == nil {
+		return nil
+	}
+
+	if err := log.ack(id); err != nil {
+		return fmt.Errorf("durable channel %q: %w", name, err)
+	}
+
+	return nil
+}
+
+// Close flushes and closes the logs behind durable channels and removes spill
+// files. Messages still in a durable channel are replayed by the next conveyer
+// that opens the same dir.
+func (c *Typed[T]) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closeLocked()
+}
+
+func (c *Typed[T]) closeLocked() error {
+	errs := make([]error, 0)
+
+	for _, name := range sortedKeys(c.durable) {
+		if err := c.durable[name].close(); err != nil {
+			errs = append(errs, fmt.Errorf("durable channel %q: %w", name, err))
+		}
+	}
+
+	for _, name := range sortedKeys(c.states) {
+		if err := c.states[name].close(); err != nil {
+			errs = append(errs, fmt.Errorf("channel %q: %w", name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
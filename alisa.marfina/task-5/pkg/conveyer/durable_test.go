@@ -0,0 +1,175 @@
+package conveyer_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func newDurable(t *testing.T, dir string, opts ...conveyer.Option) *conveyer.Conveyer {
+	t.Helper()
+
+	opts = append([]conveyer.Option{conveyer.WithDurableChannels(dir, "in", "out")}, opts...)
+	conv := conveyer.New(10, opts...)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	t.Cleanup(func() {
+		assert.NoError(t, conv.Close())
+	})
+
+	return conv
+}
+
+func stopConveyer[T any](t *testing.T, conv *conveyer.Typed[T], errCh <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := conv.Stop(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-errCh)
+}
+
+func TestDurable_ReplaysUnprocessedMessages(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	first := newDurable(t, dir)
+
+	for _, value := range []string{"a", "b", "c"} {
+		require.NoError(t, first.Send("in", value))
+	}
+
+	require.NoError(t, first.Close())
+
+	second := newDurable(t, dir)
+	_, errCh := runInBackground(t, second)
+
+	for _, want := range []string{"decorated: a", "decorated: b", "decorated: c"} {
+		value, err := second.Recv("out")
+		require.NoError(t, err)
+		assert.Equal(t, want, value)
+	}
+
+	stopConveyer(t, second, errCh)
+	require.NoError(t, second.Close())
+
+	third := newDurable(t, dir)
+
+	_, err := third.TryRecv("in")
+	require.ErrorIs(t, err, conveyer.ErrNoData)
+
+	_, err = third.TryRecv("out")
+	require.ErrorIs(t, err, conveyer.ErrNoData)
+}
+
+func TestDurable_KeepsOutputsUntilReceived(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	first := newDurable(t, dir)
+	_, errCh := runInBackground(t, first)
+
+	require.NoError(t, first.Send("in", "a"))
+	require.NoError(t, first.Send("in", "b"))
+	stopConveyer(t, first, errCh)
+
+	value, err := first.Recv("out")
+	require.NoError(t, err)
+	assert.Equal(t, "decorated: a", value)
+	require.NoError(t, first.Close())
+
+	second := newDurable(t, dir)
+
+	value, err = second.TryRecv("out")
+	require.NoError(t, err)
+	assert.Equal(t, "decorated: b", value)
+
+	_, err = second.TryRecv("in")
+	require.ErrorIs(t, err, conveyer.ErrNoData)
+}
+
+func TestDurable_FailedMessageIsRedelivered(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	first := newDurable(t, dir)
+	_, errCh := runInBackground(t, first)
+
+	require.NoError(t, first.Send("in", "ok"))
+	require.NoError(t, first.Send("in", "no decorator"))
+	require.ErrorIs(t, <-errCh, handlers.ErrCannotBeDecorated)
+	require.NoError(t, first.Close())
+
+	second := newDurable(t, dir)
+
+	_, err := second.TryRecv("out")
+	require.NoError(t, err)
+
+	value, err := second.TryRecv("in")
+	require.NoError(t, err)
+	assert.Equal(t, "no decorator", value)
+}
+
+func TestDurable_CompactsAcknowledgedSegments(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	conv := newDurable(t, dir, conveyer.WithSegmentSize(512))
+	_, errCh := runInBackground(t, conv)
+
+	for range 200 {
+		require.NoError(t, conv.Send("in", "message"))
+
+		_, err := conv.Recv("out")
+		require.NoError(t, err)
+	}
+
+	stopConveyer(t, conv, errCh)
+
+	for _, channel := range []string{"in", "out"} {
+		segments, err := filepath.Glob(filepath.Join(dir, channel, "*.log"))
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(segments), 2, channel)
+	}
+}
+
+func TestDurable_TornRecordIsIgnored(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	first := newDurable(t, dir)
+	require.NoError(t, first.Send("in", "kept"))
+	require.NoError(t, first.Close())
+
+	segments, err := filepath.Glob(filepath.Join(dir, "in", "*.log"))
+	require.NoError(t, err)
+	require.Len(t, segments, 1)
+
+	file, err := os.OpenFile(segments[0], os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"seq":2,"put":{"ID":"tor`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	second := newDurable(t, dir)
+	require.NoError(t, second.Validate())
+
+	value, err := second.TryRecv("in")
+	require.NoError(t, err)
+	assert.Equal(t, "kept", value)
+}
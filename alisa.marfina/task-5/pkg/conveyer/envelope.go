@@ -0,0 +1,84 @@
+package conveyer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"maps"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+const messageIDPrefixBytes = 8
+
+// Message IDs number messages after a prefix drawn once per process, which
+// keeps them unique across restarts without reading crypto/rand per message.
+var (
+	messageIDPrefix = newMessageIDPrefix()
+	messageIDs      atomic.Uint64
+)
+
+// Envelope carries a payload with its identity. Headers stay nil until the
+// first SetHeader, so messages without headers cost no map.
+type Envelope[T any] struct {
+	ID        string
+	CreatedAt time.Time
+	Headers   map[string]string
+	Payload   T
+}
+
+func NewEnvelope[T any](payload T) Envelope[T] {
+	return Envelope[T]{
+		ID:        newMessageID(),
+		CreatedAt: time.Now(),
+		Headers:   nil,
+		Payload:   payload,
+	}
+}
+
+func (e *Envelope[T]) SetHeader(key, value string) {
+	if e.Headers == nil {
+		e.Headers = make(map[string]string)
+	}
+
+	e.Headers[key] = value
+}
+
+func (e Envelope[T]) complete() Envelope[T] {
+	if e.ID == "" {
+		e.ID = newMessageID()
+	}
+
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+
+	return e
+}
+
+// derive carries the identity and headers of the message a handler was working
+// on over to the payload it produced.
+func (e Envelope[T]) derive(payload T) Envelope[T] {
+	return Envelope[T]{
+		ID:        e.ID,
+		CreatedAt: e.CreatedAt,
+		Headers:   maps.Clone(e.Headers),
+		Payload:   payload,
+	}
+}
+
+func newMessageID() string {
+	var buffer [2*messageIDPrefixBytes + 1 + 16]byte
+
+	id := append(buffer[:0], messageIDPrefix...)
+	id = strconv.AppendUint(id, messageIDs.Add(1), 16)
+
+	return string(id)
+}
+
+func newMessageIDPrefix() string {
+	buffer := make([]byte, messageIDPrefixBytes)
+	_, _ = rand.Read(buffer)
+
+	return hex.EncodeToString(buffer) + "-"
+}
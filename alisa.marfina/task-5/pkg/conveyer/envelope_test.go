@@ -0,0 +1,137 @@
+package conveyer_test
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func TestEnvelope_PropagatesThroughStages(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "decorated")
+	conv.RegisterSeparator(handlers.SeparatorFunc, "decorated", []string{"left", "right"})
+	conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"left", "right"}, "out")
+
+	runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	sent := make(map[string]conveyer.Envelope[string])
+
+	for index := range 5 {
+		envelope := conveyer.NewEnvelope("msg " + strconv.Itoa(index))
+		envelope.SetHeader("trace", strconv.Itoa(index))
+
+		require.NoError(t, conv.SendEnvelope(ctx, "in", envelope))
+
+		sent[envelope.ID] = envelope
+	}
+
+	for range 5 {
+		received, err := conv.RecvEnvelope(ctx, "out")
+		require.NoError(t, err)
+
+		original, found := sent[received.ID]
+		require.True(t, found, "unknown message id %s", received.ID)
+		assert.Equal(t, "decorated: "+original.Payload, received.Payload)
+		assert.Equal(t, original.Headers, received.Headers)
+		assert.True(t, original.CreatedAt.Equal(received.CreatedAt))
+	}
+}
+
+func TestEnvelope_FilteredMessageIsNotReused(t *testing.T) {
+	t.Parallel()
+
+	dropFirst := func(ctx context.Context, input, output chan string) error {
+		defer close(output)
+
+		<-input
+
+		for value := range input {
+			select {
+			case output <- value:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(dropFirst, "in", "out")
+
+	runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	dropped := conveyer.NewEnvelope("same")
+	dropped.SetHeader("trace", "dropped")
+	kept := conveyer.NewEnvelope("same")
+	kept.SetHeader("trace", "kept")
+
+	require.NoError(t, conv.SendEnvelope(ctx, "in", dropped))
+	require.NoError(t, conv.SendEnvelope(ctx, "in", kept))
+
+	received, err := conv.RecvEnvelope(ctx, "out")
+	require.NoError(t, err)
+	assert.Equal(t, kept.ID, received.ID)
+	assert.Equal(t, kept.Headers, received.Headers)
+}
+
+func TestEnvelope_MultiplexerAttributesEqualPayloads(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"left", "right"}, "out")
+
+	runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, input := range []string{"left", "right"} {
+		envelope := conveyer.NewEnvelope("same")
+		envelope.SetHeader("input", input)
+
+		require.NoError(t, conv.SendEnvelope(ctx, input, envelope))
+
+		received, err := conv.RecvEnvelope(ctx, "out")
+		require.NoError(t, err)
+		assert.Equal(t, envelope.ID, received.ID, input)
+		assert.Equal(t, envelope.Headers, received.Headers, input)
+	}
+}
+
+func TestEnvelope_SendFillsIdentity(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(2)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	require.NoError(t, conv.Send("in", "a"))
+	require.NoError(t, conv.SendEnvelope(context.Background(), "in", conveyer.Envelope[string]{Payload: "b"}))
+
+	first, err := conv.RecvEnvelope(context.Background(), "in")
+	require.NoError(t, err)
+
+	second, err := conv.RecvEnvelope(context.Background(), "in")
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, first.ID)
+	assert.NotEmpty(t, second.ID)
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.False(t, second.CreatedAt.IsZero())
+	assert.Empty(t, second.Headers)
+}
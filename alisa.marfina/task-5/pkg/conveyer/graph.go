@@ -0,0 +1,188 @@
+package conveyer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type graphOptions struct {
+	depths bool
+}
+
+type GraphOption func(*graphOptions)
+
+// WithDepths annotates every channel with the number of messages buffered in it
+// at the time of the export.
+func WithDepths() GraphOption {
+	return func(opts *graphOptions) {
+		opts.depths = true
+	}
+}
+
+type graphStage struct {
+	name       string
+	kind       stageKind
+	inputs     []string
+	outputs    []string
+	deadLetter string
+}
+
+type graph struct {
+	channels []ChannelStats
+	stages   []graphStage
+	depths   bool
+}
+
+func (c *Typed[T]) graph(opts []GraphOption) graph {
+	var options graphOptions
+
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	snapshot := graph{
+		channels: make([]ChannelStats, 0, len(c.streams)),
+		stages:   make([]graphStage, 0, len(c.stages)),
+		depths:   options.depths,
+	}
+
+	for _, name := range sortedKeys(c.streams) {
+		snapshot.channels = append(snapshot.channels, c.channelStats(name))
+	}
+
+	for _, registered := range c.stages {
+		deadLetter := ""
+		if registered.config.policy.Kind == PolicyDeadLetter {
+			deadLetter = registered.config.policy.DeadLetterChannel
+		}
+
+		snapshot.stages = append(snapshot.stages, graphStage{
+			name:       registered.name,
+			kind:       registered.kind,
+			inputs:     registered.inputs,
+			outputs:    registered.outputs,
+			deadLetter: deadLetter,
+		})
+	}
+
+	return snapshot
+}
+
+func (g graph) channelLabel(channel ChannelStats) string {
+	if g.depths {
+		return fmt.Sprintf("%s\n%d/%d", channel.Name, channel.Depth, channel.Capacity)
+	}
+
+	return fmt.Sprintf("%s\ncap %d", channel.Name, channel.Capacity)
+}
+
+func (g graph) channelIndex() map[string]int {
+	index := make(map[string]int, len(g.channels))
+
+	for position, channel := range g.channels {
+		index[channel.Name] = position
+	}
+
+	return index
+}
+
+// WriteDOT writes the registered stages and channels as a Graphviz digraph, with
+// channels drawn as ellipses and stages as boxes.
+func (c *Typed[T]) WriteDOT(writer io.Writer, opts ...GraphOption) error {
+	snapshot := c.graph(opts)
+	buffered := bufio.NewWriter(writer)
+
+	fmt.Fprintln(buffered, "digraph conveyer {")
+	fmt.Fprintln(buffered, "\trankdir=LR;")
+
+	for _, channel := range snapshot.channels {
+		fmt.Fprintf(buffered, "\t%s [shape=ellipse, label=%s];\n",
+			quoteLabel("channel:"+channel.Name), quoteLabel(snapshot.channelLabel(channel)))
+	}
+
+	for _, registered := range snapshot.stages {
+		node := quoteLabel("stage:" + registered.name)
+
+		fmt.Fprintf(buffered, "\t%s [shape=box, label=%s];\n",
+			node, quoteLabel(registered.name+"\n"+string(registered.kind)))
+
+		for _, input := range registered.inputs {
+			fmt.Fprintf(buffered, "\t%s -> %s;\n", quoteLabel("channel:"+input), node)
+		}
+
+		for _, output := range registered.outputs {
+			fmt.Fprintf(buffered, "\t%s -> %s;\n", node, quoteLabel("channel:"+output))
+		}
+
+		if registered.deadLetter != "" {
+			fmt.Fprintf(buffered, "\t%s [shape=note, label=%s];\n",
+				quoteLabel("dead-letter:"+registered.deadLetter), quoteLabel(registered.deadLetter))
+			fmt.Fprintf(buffered, "\t%s -> %s [style=dashed];\n",
+				node, quoteLabel("dead-letter:"+registered.deadLetter))
+		}
+	}
+
+	fmt.Fprintln(buffered, "}")
+
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("write dot: %w", err)
+	}
+
+	return nil
+}
+
+var mermaidEscaper = strings.NewReplacer(`"`, "#quot;", "\n", "<br/>")
+
+// WriteMermaid writes the same graph as WriteDOT as a Mermaid flowchart. Node ids
+// are positional because Mermaid ids cannot hold arbitrary channel names.
+func (c *Typed[T]) WriteMermaid(writer io.Writer, opts ...GraphOption) error {
+	snapshot := c.graph(opts)
+	channels := snapshot.channelIndex()
+	buffered := bufio.NewWriter(writer)
+
+	fmt.Fprintln(buffered, "flowchart LR")
+
+	for position, channel := range snapshot.channels {
+		fmt.Fprintf(buffered, "\tc%d([\"%s\"])\n", position, mermaidEscaper.Replace(snapshot.channelLabel(channel)))
+	}
+
+	deadLetters := make(map[string]int)
+
+	for position, registered := range snapshot.stages {
+		fmt.Fprintf(buffered, "\ts%d[\"%s\"]\n",
+			position, mermaidEscaper.Replace(registered.name+"\n"+string(registered.kind)))
+
+		for _, input := range registered.inputs {
+			fmt.Fprintf(buffered, "\tc%d --> s%d\n", channels[input], position)
+		}
+
+		for _, output := range registered.outputs {
+			fmt.Fprintf(buffered, "\ts%d --> c%d\n", position, channels[output])
+		}
+
+		if registered.deadLetter == "" {
+			continue
+		}
+
+		letter, exists := deadLetters[registered.deadLetter]
+		if !exists {
+			letter = len(deadLetters)
+			deadLetters[registered.deadLetter] = letter
+
+			fmt.Fprintf(buffered, "\td%d[/\"%s\"/]\n", letter, mermaidEscaper.Replace(registered.deadLetter))
+		}
+
+		fmt.Fprintf(buffered, "\ts%d -.-> d%d\n", position, letter)
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("write mermaid: %w", err)
+	}
+
+	return nil
+}
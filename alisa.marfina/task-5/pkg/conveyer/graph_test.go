@@ -0,0 +1,86 @@
+package conveyer_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func newGraphConveyer(t *testing.T) *conveyer.Conveyer {
+	t.Helper()
+
+	conv := conveyer.New(4)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "mid",
+		conveyer.WithName("prefix"), conveyer.WithErrorPolicy(conveyer.DeadLetterTo("failed")))
+	conv.RegisterSeparator(handlers.SeparatorFunc, "mid", []string{"left", "right"}, conveyer.WithName("split"))
+	conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"left", "right"}, "out", conveyer.WithName("join"))
+
+	require.NoError(t, conv.Send("in", "queued"))
+
+	return conv
+}
+
+func TestConveyer_WriteDOT(t *testing.T) {
+	t.Parallel()
+
+	var builder strings.Builder
+
+	require.NoError(t, newGraphConveyer(t).WriteDOT(&builder, conveyer.WithDepths()))
+
+	assert.Equal(t, `digraph conveyer {
+	rankdir=LR;
+	"channel:in" [shape=ellipse, label="in\n1/4"];
+	"channel:left" [shape=ellipse, label="left\n0/4"];
+	"channel:mid" [shape=ellipse, label="mid\n0/4"];
+	"channel:out" [shape=ellipse, label="out\n0/4"];
+	"channel:right" [shape=ellipse, label="right\n0/4"];
+	"stage:prefix" [shape=box, label="prefix\ndecorator"];
+	"channel:in" -> "stage:prefix";
+	"stage:prefix" -> "channel:mid";
+	"dead-letter:failed" [shape=note, label="failed"];
+	"stage:prefix" -> "dead-letter:failed" [style=dashed];
+	"stage:split" [shape=box, label="split\nseparator"];
+	"channel:mid" -> "stage:split";
+	"stage:split" -> "channel:left";
+	"stage:split" -> "channel:right";
+	"stage:join" [shape=box, label="join\nmultiplexer"];
+	"channel:left" -> "stage:join";
+	"channel:right" -> "stage:join";
+	"stage:join" -> "channel:out";
+}
+`, builder.String())
+}
+
+func TestConveyer_WriteMermaid(t *testing.T) {
+	t.Parallel()
+
+	var builder strings.Builder
+
+	require.NoError(t, newGraphConveyer(t).WriteMermaid(&builder))
+
+	assert.Equal(t, `flowchart LR
+	c0(["in<br/>cap 4"])
+	c1(["left<br/>cap 4"])
+	c2(["mid<br/>cap 4"])
+	c3(["out<br/>cap 4"])
+	c4(["right<br/>cap 4"])
+	s0["prefix<br/>decorator"]
+	c0 --> s0
+	s0 --> c2
+	d0[/"failed"/]
+	s0 -.-> d0
+	s1["split<br/>separator"]
+	c2 --> s1
+	s1 --> c1
+	s1 --> c4
+	s2["join<br/>multiplexer"]
+	c1 --> s2
+	c4 --> s2
+	s2 --> c3
+`, builder.String())
+}
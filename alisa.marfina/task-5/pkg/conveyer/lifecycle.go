@@ -0,0 +1,155 @@
+package conveyer
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrAlreadyRunning = errors.New("conveyer is already running")
+	ErrStopped        = errors.New("conveyer is stopped")
+)
+
+// State is the lifecycle stage of a conveyer. It moves from configuring to
+// running, draining and stopped; Reset brings a stopped conveyer back to
+// configuring.
+type State int
+
+const (
+	StateConfiguring State = iota
+	StateRunning
+	StateDraining
+	StateStopped
+)
+
+func (s State) String() string {
+	switch s {
+	case StateConfiguring:
+		return "configuring"
+	case StateRunning:
+		return "running"
+	case StateDraining:
+		return "draining"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
+func (c *Typed[T]) State() State {
+	return State(c.state.Load())
+}
+
+// setState is called with c.mu held, which serialises the transitions; the
+// value itself is atomic so that sends can check it without the lock.
+func (c *Typed[T]) setState(state State) {
+	c.state.Store(int32(state))
+}
+
+// beginRunLocked moves the conveyer into the running state and publishes how
+// Stop cancels and waits for the run, so that a Stop racing with Validate still
+// waits. It is called with c.mu held.
+func (c *Typed[T]) beginRunLocked(cancel context.CancelFunc, done <-chan struct{}) error {
+	switch c.State() {
+	case StateRunning, StateDraining:
+		return ErrAlreadyRunning
+	case StateStopped:
+		return ErrStopped
+	case StateConfiguring:
+	}
+
+	c.runCancel, c.runDone = cancel, done
+	c.setState(StateRunning)
+
+	if c.isDraining() {
+		c.setState(StateDraining)
+	}
+
+	return nil
+}
+
+// admitStage reports whether stages may be registered: on a running conveyer
+// they are started right away, a draining or stopped one accepts none.
+func (c *Typed[T]) admitStage() error {
+	switch c.State() {
+	case StateDraining:
+		return ErrDraining
+	case StateStopped:
+		return ErrStopped
+	case StateConfiguring, StateRunning:
+	}
+
+	return nil
+}
+
+// admitSend keeps reporting ErrDraining once a drained conveyer has stopped;
+// ErrStopped is left for runs that were cancelled or failed.
+func (c *Typed[T]) admitSend() error {
+	if c.isDraining() {
+		return ErrDraining
+	}
+
+	if c.State() == StateStopped {
+		return ErrStopped
+	}
+
+	return nil
+}
+
+// Reset recreates the channels of a conveyer that is not running so that it
+// can be run again with the same stages. Messages left in the old channels are
+// discarded; durable channels replay what their log has not acknowledged.
+func (c *Typed[T]) Reset() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if state := c.State(); state == StateRunning || state == StateDraining {
+		return ErrAlreadyRunning
+	}
+
+	// Senders left blocked by a cancelled run are released before sealing, or
+	// seal would wait for them forever.
+	for _, state := range c.states {
+		state.abort()
+		state.seal()
+	}
+
+	err := c.closeLocked()
+	names := sortedKeys(c.streams)
+	deadLetters := sortedKeys(c.deadLetters)
+
+	// The new drain signal is in place before the new channels are published,
+	// so senders admitted to them never see the old one.
+	draining := make(chan struct{})
+	c.draining.Store(&draining)
+	c.drainOnce = sync.Once{}
+
+	c.streams = make(map[string]chan Envelope[T])
+	c.states = make(map[string]*channelState[T])
+	c.durable = make(map[string]*durableLog[T])
+	c.durableErrs = nil
+	c.handoff = make(map[string][]Envelope[T])
+	c.deadLetters = make(map[string]chan DeadLetter[T])
+
+	for _, name := range names {
+		c.ensureChanLocked(name)
+	}
+
+	c.publishLocked()
+
+	for _, name := range deadLetters {
+		c.deadLetters[name] = make(chan DeadLetter[T], c.bufSize)
+	}
+
+	for index := range c.stages {
+		c.stages[index].control = newStageControl()
+		c.stages[index].metrics = newStageMetrics()
+	}
+
+	c.runCancel, c.runDone = nil, nil
+	c.setState(StateConfiguring)
+
+	return err
+}
@@ -0,0 +1,129 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func TestLifecycle_States(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+	assert.Equal(t, conveyer.StateConfiguring, conv.State())
+
+	_, errCh := runInBackground(t, conv)
+
+	require.Eventually(t, func() bool {
+		return conv.State() == conveyer.StateRunning
+	}, time.Second, time.Millisecond)
+
+	require.ErrorIs(t, conv.Run(context.Background()), conveyer.ErrAlreadyRunning)
+	require.ErrorIs(t, conv.Reset(), conveyer.ErrAlreadyRunning)
+
+	conv.Drain()
+	assert.Equal(t, conveyer.StateDraining, conv.State())
+
+	_, err = conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "out", "late")
+	require.ErrorIs(t, err, conveyer.ErrDraining)
+
+	require.NoError(t, <-errCh)
+	assert.Equal(t, conveyer.StateStopped, conv.State())
+	assert.Equal(t, "stopped", conv.State().String())
+
+	require.ErrorIs(t, conv.Run(context.Background()), conveyer.ErrStopped)
+
+	_, err = conv.RegisterSeparator(handlers.SeparatorFunc, "out", []string{"late"})
+	require.ErrorIs(t, err, conveyer.ErrStopped)
+}
+
+func TestLifecycle_CancelledRunRefusesSends(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	cancel, errCh := runInBackground(t, conv)
+
+	cancel()
+	require.NoError(t, <-errCh)
+	require.ErrorIs(t, conv.Send("in", "a"), conveyer.ErrStopped)
+}
+
+func TestLifecycle_ResetRunsAgain(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	for round := range 2 {
+		require.Equal(t, conveyer.StateConfiguring, conv.State(), "round %d", round)
+		require.NoError(t, conv.Send("in", "a"))
+
+		_, errCh := runInBackground(t, conv)
+
+		value, err := conv.Recv("out")
+		require.NoError(t, err)
+		assert.Equal(t, "decorated: a", value)
+
+		_, err = conv.Stop(context.Background())
+		require.NoError(t, err)
+		require.NoError(t, <-errCh)
+
+		_, err = conv.Recv("out")
+		require.ErrorIs(t, err, conveyer.ErrChannelClosed)
+		require.NoError(t, conv.Reset())
+	}
+}
+
+func TestLifecycle_ResetReleasesBlockedSenders(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+	require.NoError(t, conv.AddChannel("side", conveyer.WithCapacity(1)))
+
+	cancel, errCh := runInBackground(t, conv)
+
+	require.NoError(t, conv.Send("side", "a"))
+
+	sent := make(chan error, 1)
+
+	go func() {
+		sent <- conv.Send("side", "b")
+	}()
+
+	cancel()
+	require.NoError(t, <-errCh)
+
+	reset := make(chan error, 1)
+
+	go func() {
+		reset <- conv.Reset()
+	}()
+
+	select {
+	case err := <-reset:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "Reset waited for a blocked sender")
+	}
+
+	if err := <-sent; err != nil {
+		assert.True(t, errors.Is(err, conveyer.ErrChannelClosed) || errors.Is(err, conveyer.ErrStopped), err)
+	}
+
+	assert.Equal(t, conveyer.StateConfiguring, conv.State())
+}
@@ -0,0 +1,170 @@
+package conveyer
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var latencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+type Stats struct {
+	Channels []ChannelStats
+	Stages   []StageStats
+}
+
+type ChannelStats struct {
+	Name     string
+	Depth    int
+	Capacity int
+	Dropped  uint64
+	Spilled  int
+	// SpillErr reports why spilled messages could not be read back; they are
+	// included in Dropped.
+	SpillErr error
+}
+
+type StageStats struct {
+	Name        string
+	Kind        string
+	MessagesIn  uint64
+	MessagesOut uint64
+	Errors      uint64
+	Latency     LatencyStats
+}
+
+type LatencyStats struct {
+	Count   uint64
+	Sum     time.Duration
+	Buckets []LatencyBucket
+}
+
+type LatencyBucket struct {
+	UpperBound time.Duration
+	Count      uint64
+}
+
+type stageMetrics struct {
+	in     atomic.Uint64
+	out    atomic.Uint64
+	errors atomic.Uint64
+	// stranded counts messages a stage had already taken from its inputs but
+	// never handed to the handler before the run ended.
+	stranded atomic.Int64
+
+	mu         sync.Mutex
+	receivedAt time.Time
+	count      uint64
+	sum        time.Duration
+	bucketHits []uint64
+}
+
+func newStageMetrics() *stageMetrics {
+	return &stageMetrics{
+		in:         atomic.Uint64{},
+		out:        atomic.Uint64{},
+		errors:     atomic.Uint64{},
+		stranded:   atomic.Int64{},
+		mu:         sync.Mutex{},
+		receivedAt: time.Time{},
+		count:      0,
+		sum:        0,
+		bucketHits: make([]uint64, len(latencyBuckets)),
+	}
+}
+
+func (m *stageMetrics) received() {
+	m.in.Add(1)
+
+	m.mu.Lock()
+	m.receivedAt = time.Now()
+	m.mu.Unlock()
+}
+
+// emitted measures latency only for the first output produced after a message was
+// received, so fan-out stages are not counted twice and filtered messages not at all.
+func (m *stageMetrics) emitted() {
+	m.out.Add(1)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.receivedAt.IsZero() {
+		return
+	}
+
+	elapsed := time.Since(m.receivedAt)
+	m.receivedAt = time.Time{}
+	m.count++
+	m.sum += elapsed
+
+	for index, bound := range latencyBuckets {
+		if elapsed <= bound {
+			m.bucketHits[index]++
+		}
+	}
+}
+
+func (m *stageMetrics) snapshot() LatencyStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	buckets := make([]LatencyBucket, len(latencyBuckets))
+
+	for index, bound := range latencyBuckets {
+		buckets[index] = LatencyBucket{UpperBound: bound, Count: m.bucketHits[index]}
+	}
+
+	return LatencyStats{Count: m.count, Sum: m.sum, Buckets: buckets}
+}
+
+func (c *Typed[T]) channelStats(name string) ChannelStats {
+	channel, state := c.streams[name], c.states[name]
+
+	return ChannelStats{
+		Name:     name,
+		Depth:    len(channel),
+		Capacity: cap(channel),
+		Dropped:  state.dropped.Load(),
+		Spilled:  state.spilled(),
+		SpillErr: state.spillError(),
+	}
+}
+
+func (c *Typed[T]) Stats() Stats {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	stats := Stats{
+		Channels: make([]ChannelStats, 0, len(c.streams)),
+		Stages:   make([]StageStats, 0, len(c.stages)),
+	}
+
+	for _, name := range sortedKeys(c.streams) {
+		stats.Channels = append(stats.Channels, c.channelStats(name))
+	}
+
+	if !c.options.metrics {
+		return stats
+	}
+
+	for _, registered := range c.stages {
+		stats.Stages = append(stats.Stages, StageStats{
+			Name:        registered.name,
+			Kind:        string(registered.kind),
+			MessagesIn:  registered.metrics.in.Load(),
+			MessagesOut: registered.metrics.out.Load(),
+			Errors:      registered.metrics.errors.Load(),
+			Latency:     registered.metrics.snapshot(),
+		})
+	}
+
+	return stats
+}
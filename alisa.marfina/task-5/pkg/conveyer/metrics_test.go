@@ -0,0 +1,78 @@
+package conveyer_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func TestConveyer_Stats(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(4, conveyer.WithMetrics())
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out", conveyer.WithName("prefix"))
+
+	runInBackground(t, conv)
+
+	require.NoError(t, conv.Send("in", "a"))
+	require.NoError(t, conv.Send("in", "b"))
+
+	for range 2 {
+		_, err := conv.Recv("out")
+		require.NoError(t, err)
+	}
+
+	require.Eventually(t, func() bool {
+		stats := conv.Stats()
+
+		return len(stats.Stages) == 1 && stats.Stages[0].Latency.Count == 2
+	}, time.Second, time.Millisecond)
+
+	stats := conv.Stats()
+
+	stage := stats.Stages[0]
+	assert.Equal(t, "prefix", stage.Name)
+	assert.Equal(t, "decorator", stage.Kind)
+	assert.Equal(t, uint64(2), stage.MessagesIn)
+	assert.Equal(t, uint64(2), stage.MessagesOut)
+	assert.Zero(t, stage.Errors)
+
+	require.Len(t, stats.Channels, 2)
+	assert.Equal(t, "in", stats.Channels[0].Name)
+	assert.Equal(t, 4, stats.Channels[0].Capacity)
+}
+
+func TestMetricsHandler(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(3, conveyer.WithMetrics())
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	require.NoError(t, conv.Send("in", "waiting"))
+
+	server := httptest.NewServer(conveyer.MetricsHandler(conv))
+	defer server.Close()
+
+	response, err := http.Get(server.URL) //nolint:noctx
+	require.NoError(t, err)
+
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, response.Header.Get("Content-Type"), "text/plain")
+	assert.Contains(t, string(body), `conveyer_channel_depth{channel="in"} 1`)
+	assert.Contains(t, string(body), `conveyer_channel_capacity{channel="out"} 3`)
+	assert.Contains(t, string(body), `conveyer_stage_messages_in_total{stage="decorator_0",kind="decorator"} 0`)
+	assert.Contains(t, string(body), `conveyer_stage_latency_seconds_bucket{stage="decorator_0",le="+Inf"} 0`)
+}
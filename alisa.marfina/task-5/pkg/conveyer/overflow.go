@@ -0,0 +1,447 @@
+package conveyer
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+type OverflowPolicy int
+
+const (
+	// OverflowBlock makes writers wait for free space.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest discards the message being written to a full channel.
+	OverflowDropNewest
+	// OverflowDropOldest evicts the oldest buffered message to make room. An
+	// unbuffered channel has nothing to evict, so it drops the newest instead.
+	OverflowDropOldest
+	// OverflowSpill queues messages that do not fit in a temporary file and feeds
+	// them back in order as the channel frees up.
+	OverflowSpill
+)
+
+type channelConfig struct {
+	capacity int
+	overflow OverflowPolicy
+}
+
+type ChannelOption func(*channelConfig)
+
+func WithCapacity(capacity int) ChannelOption {
+	return func(config *channelConfig) {
+		config.capacity = capacity
+	}
+}
+
+func WithOverflow(policy OverflowPolicy) ChannelOption {
+	return func(config *channelConfig) {
+		config.overflow = policy
+	}
+}
+
+// WithSpillDir sets where OverflowSpill channels create their temporary files.
+func WithSpillDir(dir string) Option {
+	return func(opts *options) {
+		opts.spillDir = dir
+	}
+}
+
+// WithChannel overrides the capacity and overflow policy of a single channel.
+func WithChannel(name string, opts ...ChannelOption) Option {
+	return func(conveyerOptions *options) {
+		if conveyerOptions.channels == nil {
+			conveyerOptions.channels = make(map[string][]ChannelOption)
+		}
+
+		conveyerOptions.channels[name] = append(conveyerOptions.channels[name], opts...)
+	}
+}
+
+func (c *Typed[T]) channelConfig(name string) channelConfig {
+	config := channelConfig{capacity: c.bufSize, overflow: OverflowBlock}
+
+	for _, opt := range c.options.channels[name] {
+		opt(&config)
+	}
+
+	return config
+}
+
+type channelState[T any] struct {
+	overflow OverflowPolicy
+	dropped  atomic.Uint64
+	senders  atomic.Int64
+	sealed   atomic.Bool
+
+	mu       sync.Mutex
+	idle     *sync.Cond
+	spill    *spillQueue[T]
+	spillErr error
+	pumping  bool
+	closing  bool
+	closed   bool
+	stop     chan struct{}
+}
+
+func newChannelState[T any](config channelConfig) *channelState[T] {
+	state := &channelState[T]{
+		overflow: config.overflow,
+		dropped:  atomic.Uint64{},
+		senders:  atomic.Int64{},
+		sealed:   atomic.Bool{},
+		mu:       sync.Mutex{},
+		idle:     nil,
+		spill:    nil,
+		spillErr: nil,
+		pumping:  false,
+		closing:  false,
+		closed:   false,
+		stop:     make(chan struct{}),
+	}
+	state.idle = sync.NewCond(&state.mu)
+
+	return state
+}
+
+func (c *Typed[T]) stateOf(name string) *channelState[T] {
+	return c.channels().states[name]
+}
+
+// push writes to a channel according to its overflow policy. Only blocking
+// writes wait, and they give up when ctx is done, abort is closed or the
+// channel state is aborted.
+func (c *Typed[T]) push(
+	ctx context.Context,
+	name string,
+	channel chan Envelope[T],
+	envelope Envelope[T],
+	abort <-chan struct{},
+) error {
+	state := c.stateOf(name)
+	policy := state.overflow
+
+	if policy == OverflowDropOldest && cap(channel) == 0 {
+		policy = OverflowDropNewest
+	}
+
+	switch policy {
+	case OverflowDropNewest:
+		select {
+		case channel <- envelope:
+			return nil
+		default:
+			return c.drop(name, state, envelope)
+		}
+
+	case OverflowDropOldest:
+		for {
+			select {
+			case channel <- envelope:
+				return nil
+			default:
+			}
+
+			select {
+			case evicted := <-channel:
+				if err := c.drop(name, state, evicted); err != nil {
+					return err
+				}
+			default:
+			}
+		}
+
+	case OverflowSpill:
+		return c.spill(name, state, channel, envelope)
+
+	default:
+		// Most writes find room, and a two-way select is much cheaper than the
+		// full one below.
+		select {
+		case channel <- envelope:
+			return nil
+		default:
+		}
+
+		select {
+		case channel <- envelope:
+			return nil
+		case <-abort:
+			return ErrDraining
+		case <-state.stop:
+			return fmt.Errorf("send to %q: %w", name, ErrChannelClosed)
+		case <-ctx.Done():
+			return fmt.Errorf("send to %q: %w", name, ctx.Err())
+		}
+	}
+}
+
+func (c *Typed[T]) drop(name string, state *channelState[T], envelope Envelope[T]) error {
+	state.dropped.Add(1)
+
+	return c.acknowledge(name, envelope.ID)
+}
+
+func (c *Typed[T]) spill(name string, state *channelState[T], channel chan Envelope[T], envelope Envelope[T]) error {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.spill.len() == 0 {
+		select {
+		case channel <- envelope:
+			return nil
+		default:
+		}
+	}
+
+	if state.spill == nil {
+		queue, err := newSpillQueue[T](c.options.spillDir)
+		if err != nil {
+			return fmt.Errorf("spill %q: %w", name, err)
+		}
+
+		state.spill = queue
+	}
+
+	if err := state.spill.push(envelope); err != nil {
+		return fmt.Errorf("spill %q: %w", name, err)
+	}
+
+	if !state.pumping {
+		state.pumping = true
+
+		go c.pump(state, channel)
+	}
+
+	return nil
+}
+
+// pump moves spilled messages back into the channel and takes over closing it
+// when the channel was closed by Drain while messages were still spilled.
+func (c *Typed[T]) pump(state *channelState[T], channel chan Envelope[T]) {
+	for {
+		state.mu.Lock()
+
+		if state.spill.len() == 0 {
+			state.pumping = false
+
+			if state.closing && !state.closed {
+				state.closed = true
+
+				close(channel)
+			}
+
+			state.mu.Unlock()
+
+			return
+		}
+
+		envelope, err := state.spill.peek()
+		if err != nil {
+			state.fail(err)
+
+			if state.closing && !state.closed {
+				state.closed = true
+
+				close(channel)
+			}
+
+			state.mu.Unlock()
+
+			return
+		}
+
+		state.mu.Unlock()
+
+		select {
+		case channel <- envelope:
+		case <-state.stop:
+			return
+		}
+
+		state.mu.Lock()
+		state.spill.pop()
+		state.mu.Unlock()
+	}
+}
+
+// closeStreamLocked closes a channel right away unless spilled messages still
+// have to be fed into it, in which case the pump closes it once it is done. It
+// is called with c.mu held.
+func (c *Typed[T]) closeStreamLocked(name string) {
+	state := c.states[name]
+	state.seal()
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.closed {
+		return
+	}
+
+	if state.pumping {
+		state.closing = true
+
+		return
+	}
+
+	state.closed = true
+
+	close(c.streams[name])
+}
+
+// fail gives up on a spill file that can no longer be read: the messages still
+// in it are counted as dropped and the next overflow starts a new file. It is
+// called with s.mu held.
+func (s *channelState[T]) fail(err error) {
+	s.dropped.Add(uint64(s.spill.len()))
+	s.spillErr = errors.Join(s.spillErr, err, s.spill.close())
+	s.spill = nil
+	s.pumping = false
+}
+
+func (s *channelState[T]) spillError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.spillErr
+}
+
+func (s *channelState[T]) spilled() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.spill.len()
+}
+
+// abort releases the senders blocked on a full channel and stops the pump, so
+// that the channel can be sealed.
+func (s *channelState[T]) abort() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.abortLocked()
+}
+
+func (s *channelState[T]) abortLocked() {
+	select {
+	case <-s.stop:
+	default:
+		close(s.stop)
+	}
+}
+
+func (s *channelState[T]) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.abortLocked()
+
+	return s.spill.close()
+}
+
+// spillQueue is a FIFO of envelopes in a temporary file removed on close. The
+// file is truncated whenever the queue runs empty, so it only grows for as long
+// as the channel stays full.
+type spillQueue[T any] struct {
+	file   *os.File
+	reader *bufio.Reader
+	length int
+	head   *Envelope[T]
+}
+
+func newSpillQueue[T any](dir string) (*spillQueue[T], error) {
+	file, err := os.CreateTemp(dir, "conveyer-spill-*")
+	if err != nil {
+		return nil, fmt.Errorf("create spill file: %w", err)
+	}
+
+	return &spillQueue[T]{
+		file:   file,
+		reader: bufio.NewReader(io.NewSectionReader(file, 0, 1<<62)),
+		length: 0,
+		head:   nil,
+	}, nil
+}
+
+func (q *spillQueue[T]) len() int {
+	if q == nil {
+		return 0
+	}
+
+	return q.length
+}
+
+func (q *spillQueue[T]) push(envelope Envelope[T]) error {
+	line, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("encode message: %w", err)
+	}
+
+	if _, err := q.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("write spill file: %w", err)
+	}
+
+	q.length++
+
+	return nil
+}
+
+func (q *spillQueue[T]) peek() (Envelope[T], error) {
+	if q.head != nil {
+		return *q.head, nil
+	}
+
+	line, err := q.reader.ReadBytes('\n')
+	if err != nil {
+		return Envelope[T]{}, fmt.Errorf("read spill file: %w", err)
+	}
+
+	var envelope Envelope[T]
+
+	if err := json.Unmarshal(line, &envelope); err != nil {
+		return Envelope[T]{}, fmt.Errorf("decode message: %w", err)
+	}
+
+	q.head = &envelope
+
+	return envelope, nil
+}
+
+func (q *spillQueue[T]) pop() {
+	q.head = nil
+	q.length--
+
+	if q.length > 0 {
+		return
+	}
+
+	if err := q.file.Truncate(0); err == nil {
+		_, _ = q.file.Seek(0, io.SeekStart)
+		q.reader.Reset(io.NewSectionReader(q.file, 0, 1<<62))
+	}
+}
+
+func (q *spillQueue[T]) close() error {
+	if q == nil {
+		return nil
+	}
+
+	name := q.file.Name()
+
+	if err := q.file.Close(); err != nil {
+		return fmt.Errorf("close spill file: %w", err)
+	}
+
+	if err := os.Remove(name); err != nil {
+		return fmt.Errorf("remove spill file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,212 @@
+package conveyer_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func TestOverflow_DropPolicies(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		policy   conveyer.OverflowPolicy
+		expected []string
+	}{
+		{name: "drop newest", policy: conveyer.OverflowDropNewest, expected: []string{"a", "b"}},
+		{name: "drop oldest", policy: conveyer.OverflowDropOldest, expected: []string{"c", "d"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			conv := conveyer.New(10, conveyer.WithChannel("in",
+				conveyer.WithCapacity(2), conveyer.WithOverflow(test.policy)))
+			conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+			require.NoError(t, conv.Send("in", "a"))
+			require.NoError(t, conv.Send("in", "b"))
+			require.NoError(t, conv.TrySend("in", "c"))
+			require.NoError(t, conv.Send("in", "d"))
+
+			stats := conv.Stats().Channels[0]
+			assert.Equal(t, 2, stats.Capacity)
+			assert.Equal(t, uint64(2), stats.Dropped)
+
+			received := make([]string, 0, 2)
+
+			for range 2 {
+				value, err := conv.TryRecv("in")
+				require.NoError(t, err)
+
+				received = append(received, value)
+			}
+
+			assert.Equal(t, test.expected, received)
+		})
+	}
+}
+
+func TestOverflow_UnbufferedDropOldestDropsNewest(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(0, conveyer.WithChannel("in", conveyer.WithOverflow(conveyer.OverflowDropOldest)))
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	sent := make(chan error, 1)
+
+	go func() {
+		sent <- conv.Send("in", "a")
+	}()
+
+	select {
+	case err := <-sent:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "Send kept evicting from an unbuffered channel")
+	}
+
+	assert.Equal(t, uint64(1), conv.Stats().Channels[0].Dropped)
+}
+
+func TestOverflow_SlowBranchDoesNotStall(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10, conveyer.WithChannel("slow",
+		conveyer.WithCapacity(1), conveyer.WithOverflow(conveyer.OverflowDropNewest)))
+	conv.RegisterSeparator(handlers.BroadcastSeparator[string](), "in", []string{"fast", "slow"})
+
+	runInBackground(t, conv)
+
+	for range 20 {
+		require.NoError(t, conv.Send("in", "message"))
+
+		_, err := conv.Recv("fast")
+		require.NoError(t, err)
+	}
+
+	assert.Eventually(t, func() bool {
+		return conv.Stats().Channels[2].Dropped == 19
+	}, time.Second, time.Millisecond)
+}
+
+func TestOverflow_SpillKeepsOrder(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	conv := conveyer.New(10, conveyer.WithSpillDir(dir), conveyer.WithChannel("in",
+		conveyer.WithCapacity(1), conveyer.WithOverflow(conveyer.OverflowSpill)))
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	values := []string{"a", "b", "c", "d", "e"}
+
+	for _, value := range values {
+		require.NoError(t, conv.Send("in", value))
+	}
+
+	stats := conv.Stats().Channels[0]
+	assert.Equal(t, 1, stats.Depth)
+	assert.Equal(t, 4, stats.Spilled)
+
+	_, errCh := runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	_, err := conv.Stop(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-errCh)
+
+	received := make([]string, 0, len(values))
+
+	for value, err := range conv.Stream("out") {
+		require.NoError(t, err)
+
+		received = append(received, value)
+	}
+
+	assert.Equal(t, []string{
+		"decorated: a", "decorated: b", "decorated: c", "decorated: d", "decorated: e",
+	}, received)
+
+	require.NoError(t, conv.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+var errUnreadable = errors.New("unreadable payload")
+
+// brittle payloads spill fine, but "bad" cannot be read back from the file.
+type brittle string
+
+func (b *brittle) UnmarshalJSON(data []byte) error {
+	var value string
+
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	if value == "bad" {
+		return errUnreadable
+	}
+
+	*b = brittle(value)
+
+	return nil
+}
+
+func TestOverflow_UnreadableSpillClosesChannel(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.NewTyped[brittle](10, conveyer.WithSpillDir(t.TempDir()), conveyer.WithChannel("in",
+		conveyer.WithCapacity(1), conveyer.WithOverflow(conveyer.OverflowSpill)))
+	conv.RegisterDecorator(handlers.Decorator(func(value brittle) (brittle, error) {
+		return value, nil
+	}), "in", "out")
+
+	for _, value := range []brittle{"a", "b", "bad", "c"} {
+		require.NoError(t, conv.Send("in", value))
+	}
+
+	// The pump is still holding "b", so the channel is closed only once the
+	// spill file has been read back.
+	conv.Drain()
+
+	_, errCh := runInBackground(t, conv)
+
+	select {
+	case err := <-errCh:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.FailNow(t, "Run did not return after the spill file failed")
+	}
+
+	received := make([]brittle, 0, 2)
+
+	for value, err := range conv.Stream("out") {
+		require.NoError(t, err)
+
+		received = append(received, value)
+	}
+
+	assert.Equal(t, []brittle{"a", "b"}, received)
+
+	stats := conv.Stats().Channels[0]
+	assert.Equal(t, uint64(2), stats.Dropped)
+	assert.Equal(t, 0, stats.Spilled)
+	require.ErrorIs(t, stats.SpillErr, errUnreadable)
+	require.NoError(t, conv.Close())
+}
@@ -0,0 +1,37 @@
+package conveyer
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+var ErrHandlerPanic = errors.New("handler panicked")
+
+// PanicError is the error a stage fails with when its handler panics. It goes
+// through the stage's error policy like any other handler error.
+type PanicError struct {
+	Stage string
+	Value any
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("stage %q: %v: %v", e.Stage, ErrHandlerPanic, e.Value)
+}
+
+func (e *PanicError) Unwrap() error {
+	return ErrHandlerPanic
+}
+
+// invokeRecovered runs a handler and turns a panic in its goroutine into a
+// PanicError. Panics in goroutines the handler starts itself cannot be caught.
+func invokeRecovered(stage string, invoke func() error) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &PanicError{Stage: stage, Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+
+	return invoke()
+}
@@ -0,0 +1,80 @@
+package conveyer_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func panicOn(trigger string) func(context.Context, chan string, chan string) error {
+	return handlers.Map(func(value string) string {
+		if value == trigger {
+			panic("boom")
+		}
+
+		return value
+	})
+}
+
+func TestRun_RecoversHandlerPanic(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(panicOn("boom"), "in", "out", conveyer.WithName("fragile"))
+
+	_, errCh := runInBackground(t, conv)
+
+	require.NoError(t, conv.Send("in", "boom"))
+
+	err := <-errCh
+	require.ErrorIs(t, err, conveyer.ErrHandlerPanic)
+
+	var panicErr *conveyer.PanicError
+
+	require.ErrorAs(t, err, &panicErr)
+	assert.Equal(t, "fragile", panicErr.Stage)
+	assert.Equal(t, "boom", panicErr.Value)
+	assert.NotEmpty(t, panicErr.Stack)
+}
+
+func TestRun_PanicFollowsErrorPolicy(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(panicOn("boom"), "in", "out", conveyer.WithErrorPolicy(conveyer.SkipMessage()))
+
+	runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	for _, value := range []string{"a", "boom", "b"} {
+		require.NoError(t, conv.Send("in", value))
+	}
+
+	for _, expected := range []string{"a", "b"} {
+		value, err := conv.RecvContext(ctx, "out")
+		require.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+}
+
+func TestRun_RecoversInjectedChaos(t *testing.T) {
+	t.Parallel()
+
+	chaos := handlers.ChaosDecorator(handlers.Chaos{PanicRate: 1}, handlers.PrefixDecoratorFunc)
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(chaos, "in", "out")
+
+	_, errCh := runInBackground(t, conv)
+
+	require.NoError(t, conv.Send("in", "a"))
+	require.ErrorIs(t, <-errCh, conveyer.ErrHandlerPanic)
+}
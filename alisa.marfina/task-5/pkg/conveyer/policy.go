@@ -0,0 +1,168 @@
+package conveyer
+
+import (
+	"time"
+)
+
+type PolicyKind int
+
+const (
+	PolicyFailFast PolicyKind = iota
+	PolicyRestart
+	PolicySkip
+	PolicyDeadLetter
+)
+
+const (
+	defaultInitialBackoff = 10 * time.Millisecond
+	defaultMaxBackoff     = time.Second
+	backoffMultiplier     = 2
+)
+
+type ErrorPolicy struct {
+	Kind              PolicyKind
+	InitialBackoff    time.Duration
+	MaxBackoff        time.Duration
+	MaxRestarts       int
+	DeadLetterChannel string
+}
+
+func FailFast() ErrorPolicy {
+	return ErrorPolicy{
+		Kind:              PolicyFailFast,
+		InitialBackoff:    0,
+		MaxBackoff:        0,
+		MaxRestarts:       0,
+		DeadLetterChannel: "",
+	}
+}
+
+// RestartWithBackoff restarts a failed handler and redelivers the message it was
+// processing. A non-positive maxRestarts allows unlimited restarts.
+func RestartWithBackoff(initial, maxBackoff time.Duration, maxRestarts int) ErrorPolicy {
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+
+	if maxBackoff <= 0 {
+		maxBackoff = defaultMaxBackoff
+	}
+
+	maxBackoff = max(maxBackoff, initial)
+
+	return ErrorPolicy{
+		Kind:              PolicyRestart,
+		InitialBackoff:    initial,
+		MaxBackoff:        maxBackoff,
+		MaxRestarts:       maxRestarts,
+		DeadLetterChannel: "",
+	}
+}
+
+func SkipMessage() ErrorPolicy {
+	return ErrorPolicy{
+		Kind:              PolicySkip,
+		InitialBackoff:    0,
+		MaxBackoff:        0,
+		MaxRestarts:       0,
+		DeadLetterChannel: "",
+	}
+}
+
+func DeadLetterTo(channel string) ErrorPolicy {
+	return ErrorPolicy{
+		Kind:              PolicyDeadLetter,
+		InitialBackoff:    0,
+		MaxBackoff:        0,
+		MaxRestarts:       0,
+		DeadLetterChannel: channel,
+	}
+}
+
+func (p ErrorPolicy) nextBackoff(current time.Duration) time.Duration {
+	if current == 0 {
+		return p.InitialBackoff
+	}
+
+	return min(current*backoffMultiplier, p.MaxBackoff)
+}
+
+type stageConfig struct {
+	name   string
+	policy ErrorPolicy
+}
+
+type StageOption func(*stageConfig)
+
+func WithErrorPolicy(policy ErrorPolicy) StageOption {
+	return func(config *stageConfig) {
+		config.policy = policy
+	}
+}
+
+func WithName(name string) StageOption {
+	return func(config *stageConfig) {
+		config.name = name
+	}
+}
+
+func newStageConfig(opts []StageOption) stageConfig {
+	config := stageConfig{name: "", policy: FailFast()}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return config
+}
+
+type DeadLetter[T any] struct {
+	Envelope[T]
+
+	Input string
+	Err   error
+}
+
+func (c *Typed[T]) ensureDeadLetter(name string) chan DeadLetter[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.ensureDeadLetterLocked(name)
+}
+
+func (c *Typed[T]) ensureDeadLetterLocked(name string) chan DeadLetter[T] {
+	if channel, exists := c.deadLetters[name]; exists {
+		return channel
+	}
+
+	channel := make(chan DeadLetter[T], c.bufSize)
+	c.deadLetters[name] = channel
+
+	return channel
+}
+
+func (c *Typed[T]) RecvDeadLetter(name string) (DeadLetter[T], error) {
+	c.mu.RLock()
+	channel, exists := c.deadLetters[name]
+	c.mu.RUnlock()
+
+	if !exists {
+		return DeadLetter[T]{}, ErrChanNotFound
+	}
+
+	letter, isOpen := <-channel
+	if !isOpen {
+		return DeadLetter[T]{}, ErrChannelClosed
+	}
+
+	return letter, nil
+}
+
+func (c *Typed[T]) closeDeadLetters() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, channel := range c.deadLetters {
+		close(channel)
+	}
+}
@@ -0,0 +1,187 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+var errFlaky = errors.New("flaky failure")
+
+func runInBackground[T any](t *testing.T, conv *conveyer.Typed[T]) (context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- conv.Run(ctx)
+	}()
+
+	t.Cleanup(cancel)
+
+	return cancel, errCh
+}
+
+func TestErrorPolicy_Skip(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(5)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.SkipMessage()))
+
+	runInBackground(t, conv)
+
+	for _, value := range []string{"a", "no decorator", "b"} {
+		require.NoError(t, conv.Send("in", value))
+	}
+
+	for _, expected := range []string{"decorated: a", "decorated: b"} {
+		value, err := conv.Recv("out")
+		require.NoError(t, err)
+		assert.Equal(t, expected, value)
+	}
+}
+
+func TestErrorPolicy_DeadLetter(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(5)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.DeadLetterTo("failed")))
+
+	runInBackground(t, conv)
+
+	require.NoError(t, conv.Send("in", "no decorator here"))
+	require.NoError(t, conv.Send("in", "fine"))
+
+	letter, err := conv.RecvDeadLetter("failed")
+	require.NoError(t, err)
+	assert.Equal(t, "in", letter.Input)
+	assert.Equal(t, "no decorator here", letter.Payload)
+	require.ErrorIs(t, letter.Err, handlers.ErrCannotBeDecorated)
+
+	value, err := conv.Recv("out")
+	require.NoError(t, err)
+	assert.Equal(t, "decorated: fine", value)
+}
+
+func TestErrorPolicy_RestartRedelivers(t *testing.T) {
+	t.Parallel()
+
+	var failures atomic.Int32
+
+	flaky := handlers.Decorator(func(value string) (string, error) {
+		if failures.Add(1) <= 2 {
+			return "", errFlaky
+		}
+
+		return value + "!", nil
+	})
+
+	conv := conveyer.New(5)
+	conv.RegisterDecorator(flaky, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.RestartWithBackoff(time.Millisecond, 5*time.Millisecond, 0)))
+
+	runInBackground(t, conv)
+
+	require.NoError(t, conv.Send("in", "retry me"))
+
+	value, err := conv.Recv("out")
+	require.NoError(t, err)
+	assert.Equal(t, "retry me!", value)
+}
+
+func TestErrorPolicy_RestartLimitCountsConsecutiveFailures(t *testing.T) {
+	t.Parallel()
+
+	var mu sync.Mutex
+
+	seen := make(map[string]bool)
+
+	// Every message fails once and succeeds when redelivered.
+	failsOnce := handlers.Decorator(func(value string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if !seen[value] {
+			seen[value] = true
+
+			return "", errFlaky
+		}
+
+		return value + "!", nil
+	})
+
+	conv := conveyer.New(5)
+	conv.RegisterDecorator(failsOnce, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.RestartWithBackoff(time.Millisecond, time.Millisecond, 1)))
+
+	cancel, errCh := runInBackground(t, conv)
+
+	for _, value := range []string{"first", "second", "third"} {
+		require.NoError(t, conv.Send("in", value))
+
+		received, err := conv.Recv("out")
+		require.NoError(t, err)
+		assert.Equal(t, value+"!", received)
+	}
+
+	cancel()
+	require.NoError(t, <-errCh)
+}
+
+func TestErrorPolicy_CancelDuringBackoffReturnsHandlerError(t *testing.T) {
+	t.Parallel()
+
+	failed := make(chan struct{}, 1)
+	alwaysFails := handlers.Decorator(func(string) (string, error) {
+		select {
+		case failed <- struct{}{}:
+		default:
+		}
+
+		return "", errFlaky
+	})
+
+	conv := conveyer.New(5)
+	conv.RegisterDecorator(alwaysFails, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.RestartWithBackoff(time.Hour, time.Hour, 0)))
+
+	cancel, errCh := runInBackground(t, conv)
+
+	require.NoError(t, conv.Send("in", "poison"))
+	<-failed
+
+	cancel()
+	require.ErrorIs(t, <-errCh, errFlaky)
+}
+
+func TestErrorPolicy_RestartLimit(t *testing.T) {
+	t.Parallel()
+
+	alwaysFails := handlers.Decorator(func(string) (string, error) {
+		return "", errFlaky
+	})
+
+	conv := conveyer.New(5)
+	conv.RegisterDecorator(alwaysFails, "in", "out",
+		conveyer.WithErrorPolicy(conveyer.RestartWithBackoff(time.Millisecond, time.Millisecond, 2)))
+
+	_, errCh := runInBackground(t, conv)
+
+	require.NoError(t, conv.Send("in", "poison"))
+
+	err := <-errCh
+	require.ErrorIs(t, err, conveyer.ErrTooManyRestarts)
+	require.ErrorIs(t, err, errFlaky)
+}
@@ -0,0 +1,128 @@
+package conveyer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type StatsSource interface {
+	Stats() Stats
+}
+
+func MetricsHandler(source StatsSource) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, _ *http.Request) {
+		writer.Header().Set("Content-Type", prometheusContentType)
+
+		if err := WritePrometheus(writer, source.Stats()); err != nil {
+			http.Error(writer, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+func WritePrometheus(writer io.Writer, stats Stats) error {
+	buffered := bufio.NewWriter(writer)
+
+	writeHeader(buffered, "conveyer_channel_depth", "gauge", "Number of messages buffered in a channel.")
+
+	for _, channel := range stats.Channels {
+		fmt.Fprintf(buffered, "conveyer_channel_depth{channel=%s} %d\n", quoteLabel(channel.Name), channel.Depth)
+	}
+
+	writeHeader(buffered, "conveyer_channel_capacity", "gauge", "Buffer capacity of a channel.")
+
+	for _, channel := range stats.Channels {
+		fmt.Fprintf(buffered, "conveyer_channel_capacity{channel=%s} %d\n", quoteLabel(channel.Name), channel.Capacity)
+	}
+
+	writeHeader(buffered, "conveyer_channel_spilled", "gauge", "Number of messages spilled to disk for a channel.")
+
+	for _, channel := range stats.Channels {
+		fmt.Fprintf(buffered, "conveyer_channel_spilled{channel=%s} %d\n", quoteLabel(channel.Name), channel.Spilled)
+	}
+
+	writeHeader(buffered, "conveyer_channel_dropped_total", "counter", "Messages dropped by a channel overflow policy.")
+
+	for _, channel := range stats.Channels {
+		fmt.Fprintf(buffered, "conveyer_channel_dropped_total{channel=%s} %d\n", quoteLabel(channel.Name), channel.Dropped)
+	}
+
+	if len(stats.Stages) > 0 {
+		writeStages(buffered, stats.Stages)
+	}
+
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("write metrics: %w", err)
+	}
+
+	return nil
+}
+
+func writeStages(writer io.Writer, stages []StageStats) {
+	counters := []struct {
+		name  string
+		help  string
+		value func(StageStats) uint64
+	}{
+		{
+			name:  "conveyer_stage_messages_in_total",
+			help:  "Messages received by a stage.",
+			value: func(stage StageStats) uint64 { return stage.MessagesIn },
+		},
+		{
+			name:  "conveyer_stage_messages_out_total",
+			help:  "Messages emitted by a stage.",
+			value: func(stage StageStats) uint64 { return stage.MessagesOut },
+		},
+		{
+			name:  "conveyer_stage_errors_total",
+			help:  "Errors returned by a stage handler.",
+			value: func(stage StageStats) uint64 { return stage.Errors },
+		},
+	}
+
+	for _, counter := range counters {
+		writeHeader(writer, counter.name, "counter", counter.help)
+
+		for _, stage := range stages {
+			fmt.Fprintf(writer, "%s{stage=%s,kind=%s} %d\n",
+				counter.name, quoteLabel(stage.Name), quoteLabel(stage.Kind), counter.value(stage))
+		}
+	}
+
+	const histogram = "conveyer_stage_latency_seconds"
+
+	writeHeader(writer, histogram, "histogram", "Time from a stage receiving a message to emitting its result.")
+
+	for _, stage := range stages {
+		labels := "stage=" + quoteLabel(stage.Name)
+
+		for _, bucket := range stage.Latency.Buckets {
+			fmt.Fprintf(writer, "%s_bucket{%s,le=%q} %d\n",
+				histogram, labels, formatFloat(bucket.UpperBound.Seconds()), bucket.Count)
+		}
+
+		fmt.Fprintf(writer, "%s_bucket{%s,le=\"+Inf\"} %d\n", histogram, labels, stage.Latency.Count)
+		fmt.Fprintf(writer, "%s_sum{%s} %s\n", histogram, labels, formatFloat(stage.Latency.Sum.Seconds()))
+		fmt.Fprintf(writer, "%s_count{%s} %d\n", histogram, labels, stage.Latency.Count)
+	}
+}
+
+func writeHeader(writer io.Writer, name, metricType, help string) {
+	fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func quoteLabel(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
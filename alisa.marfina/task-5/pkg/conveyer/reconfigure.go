@@ -0,0 +1,259 @@
+package conveyer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+var (
+	ErrStageNotFound    = errors.New("stage not found")
+	ErrChannelExists    = errors.New("channel already exists")
+	ErrChannelInUse     = errors.New("channel is used by a stage")
+	ErrChannelNotEmpty  = errors.New("channel still holds messages")
+	ErrStageNotDetached = errors.New("stage did not detach")
+)
+
+// StageHandle refers to a registered stage and stays valid after the stage is
+// detached; detaching it again reports ErrStageNotFound.
+type StageHandle[T any] struct {
+	conveyer *Typed[T]
+	id       int
+	name     string
+}
+
+func (h *StageHandle[T]) Name() string {
+	return h.name
+}
+
+// Detach removes the stage from the conveyer. On a running conveyer the stage
+// stops reading its inputs, lets the handler finish the messages it already
+// has and leaves its outputs open; messages it had read ahead are handed to the
+// next stage that reads the same channel. Detach waits for that until ctx ends.
+func (h *StageHandle[T]) Detach(ctx context.Context) error {
+	return h.conveyer.detach(ctx, h.id)
+}
+
+type stageControl struct {
+	detach     chan struct{}
+	detachOnce sync.Once
+	done       chan struct{}
+	started    bool
+}
+
+func newStageControl() *stageControl {
+	return &stageControl{
+		detach:     make(chan struct{}),
+		detachOnce: sync.Once{},
+		done:       make(chan struct{}),
+		started:    false,
+	}
+}
+
+func (s *stageControl) detached() bool {
+	select {
+	case <-s.detach:
+		return true
+	default:
+		return false
+	}
+}
+
+type runState struct {
+	ctx          context.Context //nolint:containedctx
+	group        *errgroup.Group
+	live         int
+	lastDetached bool
+	idle         chan struct{}
+	released     bool
+}
+
+func newRunState(ctx context.Context, group *errgroup.Group) *runState {
+	return &runState{
+		ctx:          ctx,
+		group:        group,
+		live:         0,
+		lastDetached: false,
+		idle:         make(chan struct{}),
+		released:     false,
+	}
+}
+
+// startLocked starts a stage on the current run. It is called with c.mu held.
+func (c *Typed[T]) startLocked(registered stage[T]) {
+	run := c.run
+	if run.released || run.ctx.Err() != nil {
+		return
+	}
+
+	run.live++
+	registered.control.started = true
+	inputs, outputs := c.bindLocked(registered.inputs), c.bindLocked(registered.outputs)
+
+	run.group.Go(func() error {
+		defer close(registered.control.done)
+
+		err := c.supervise(run.ctx, registered, inputs, outputs)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		run.live--
+		run.lastDetached = registered.control.detached()
+		c.checkIdleLocked()
+
+		return err
+	})
+}
+
+// checkIdleLocked ends the run once no stage is left, unless the last ones were
+// detached on purpose: then the run waits for replacements until it is drained.
+func (c *Typed[T]) checkIdleLocked() {
+	run := c.run
+	if run == nil || run.released || run.live > 0 {
+		return
+	}
+
+	if run.lastDetached && !c.isDraining() {
+		return
+	}
+
+	run.released = true
+	close(run.idle)
+}
+
+func (c *Typed[T]) detach(ctx context.Context, id int) error {
+	c.mu.Lock()
+
+	index := slices.IndexFunc(c.stages, func(registered stage[T]) bool {
+		return registered.id == id
+	})
+	if index < 0 {
+		c.mu.Unlock()
+
+		return ErrStageNotFound
+	}
+
+	registered := c.stages[index]
+	control := registered.control
+
+	if !control.started {
+		c.stages = slices.Delete(c.stages, index, index+1)
+		c.mu.Unlock()
+
+		return nil
+	}
+
+	control.detachOnce.Do(func() {
+		close(control.detach)
+	})
+	c.mu.Unlock()
+
+	select {
+	case <-control.done:
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %q: %w", ErrStageNotDetached, registered.name, ctx.Err())
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.stages = slices.DeleteFunc(c.stages, func(candidate stage[T]) bool {
+		return candidate.id == id
+	})
+
+	return nil
+}
+
+// handOff keeps messages a detached stage had read but not processed.
+func (c *Typed[T]) handOff(name string, envelopes []Envelope[T]) {
+	if len(envelopes) == 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.handoff[name] = append(c.handoff[name], envelopes...)
+}
+
+func (c *Typed[T]) takeHandOff(name string) []Envelope[T] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	envelopes := c.handoff[name]
+	delete(c.handoff, name)
+
+	return envelopes
+}
+
+// AddChannel creates a channel with its own capacity and overflow policy, which
+// is mostly useful before hot-adding a stage that writes to it.
+func (c *Typed[T]) AddChannel(name string, opts ...ChannelOption) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, exists := c.streams[name]; exists {
+		return fmt.Errorf("%w: %q", ErrChannelExists, name)
+	}
+
+	if c.options.channels == nil {
+		c.options.channels = make(map[string][]ChannelOption)
+	}
+
+	c.options.channels[name] = append(c.options.channels[name], opts...)
+	c.ensureChanLocked(name)
+
+	return nil
+}
+
+// RemoveChannel deletes a channel that no stage uses and that holds no messages.
+// Receivers still waiting on it get ErrChannelClosed.
+func (c *Typed[T]) RemoveChannel(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	channel, exists := c.streams[name]
+	if !exists {
+		return ErrChanNotFound
+	}
+
+	for _, registered := range c.stages {
+		if slices.Contains(registered.inputs, name) || slices.Contains(registered.outputs, name) {
+			return fmt.Errorf("%w: %q by %q", ErrChannelInUse, name, registered.name)
+		}
+	}
+
+	// Sealing first keeps senders from filling the channel after the check. A
+	// sender already admitted is a message in flight, so the channel is not
+	// empty; waiting for it could take forever if the channel is full.
+	state := c.states[name]
+
+	if !state.trySeal() || len(channel) > 0 || state.spilled() > 0 || len(c.handoff[name]) > 0 {
+		state.unseal()
+
+		return fmt.Errorf("%w: %q", ErrChannelNotEmpty, name)
+	}
+
+	var errs []error
+
+	if log, isDurable := c.durable[name]; isDurable {
+		errs = append(errs, log.close())
+		delete(c.durable, name)
+	}
+
+	c.closeStreamLocked(name)
+	errs = append(errs, state.close())
+
+	delete(c.streams, name)
+	delete(c.states, name)
+	delete(c.sources, name)
+	delete(c.sinks, name)
+	c.publishLocked()
+
+	return errors.Join(errs...)
+}
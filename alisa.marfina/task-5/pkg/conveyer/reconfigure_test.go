@@ -0,0 +1,284 @@
+package conveyer_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func prefixWith(prefix string) func(context.Context, chan string, chan string) error {
+	return handlers.Decorator(func(value string) (string, error) {
+		return prefix + value, nil
+	})
+}
+
+func TestReconfigure_HotSwapDecorator(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	old, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	_, errCh := runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, conv.Send("in", "a"))
+
+	value, err := conv.RecvContext(ctx, "out")
+	require.NoError(t, err)
+	assert.Equal(t, "decorated: a", value)
+
+	require.NoError(t, old.Detach(ctx))
+	require.ErrorIs(t, old.Detach(ctx), conveyer.ErrStageNotFound)
+
+	_, err = conv.RegisterDecorator(handlers.Decorator(func(value string) (string, error) {
+		return strings.ToUpper(value), nil
+	}), "in", "out")
+	require.NoError(t, err)
+
+	require.NoError(t, conv.Send("in", "b"))
+
+	value, err = conv.RecvContext(ctx, "out")
+	require.NoError(t, err)
+	assert.Equal(t, "B", value)
+
+	_, err = conv.Stop(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-errCh)
+
+	_, err = conv.Recv("out")
+	require.ErrorIs(t, err, conveyer.ErrChannelClosed)
+}
+
+func TestReconfigure_DetachHandsOffBufferedMessages(t *testing.T) {
+	t.Parallel()
+
+	busy, release := make(chan struct{}, 3), make(chan struct{})
+	blocking := func(ctx context.Context, input, output chan string) error {
+		defer close(output)
+
+		for value := range input {
+			busy <- struct{}{}
+			<-release
+
+			select {
+			case output <- "old: " + value:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	}
+
+	conv := conveyer.New(10)
+	old, err := conv.RegisterDecorator(blocking, "in", "out")
+	require.NoError(t, err)
+
+	_, errCh := runInBackground(t, conv)
+
+	for _, value := range []string{"m1", "m2", "m3"} {
+		require.NoError(t, conv.Send("in", value))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	<-busy
+
+	detaching, detached := make(chan struct{}), make(chan error, 1)
+
+	go func() {
+		close(detaching)
+		detached <- old.Detach(ctx)
+	}()
+
+	<-detaching
+	close(release)
+	require.NoError(t, <-detached)
+
+	_, err = conv.RegisterDecorator(prefixWith("new: "), "in", "out")
+	require.NoError(t, err)
+
+	received := make([]string, 0, 3)
+
+	for range 3 {
+		value, err := conv.RecvContext(ctx, "out")
+		require.NoError(t, err)
+
+		received = append(received, value)
+	}
+
+	// Which handler got m2 and m3 depends on when the old one saw the detach, but
+	// nothing may be lost or reordered either way.
+	assert.Equal(t, "old: m1", received[0])
+
+	for index, suffix := range []string{": m1", ": m2", ": m3"} {
+		assert.True(t, strings.HasSuffix(received[index], suffix), received[index])
+	}
+
+	_, err = conv.Stop(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-errCh)
+}
+
+func TestReconfigure_RunWaitsForReplacement(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	handle, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	_, errCh := runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	require.NoError(t, conv.Send("in", "warm-up"))
+
+	_, err = conv.RecvContext(ctx, "out")
+	require.NoError(t, err)
+	require.NoError(t, handle.Detach(ctx))
+
+	select {
+	case err := <-errCh:
+		t.Fatalf("run returned after its last stage was detached: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	_, err = conv.RegisterSeparator(handlers.SeparatorFunc, "in", []string{"out"})
+	require.NoError(t, err)
+	require.NoError(t, conv.Send("in", "a"))
+
+	value, err := conv.RecvContext(ctx, "out")
+	require.NoError(t, err)
+	assert.Equal(t, "a", value)
+
+	conv.Drain()
+	require.NoError(t, <-errCh)
+}
+
+func TestReconfigure_RejectsInvalidStages(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		input    string
+		output   string
+		expected error
+		channels []string
+	}{
+		{
+			name: "second writer", input: "b", output: "out",
+			expected: conveyer.ErrMultipleWriters, channels: []string{"out"},
+		},
+		{
+			name: "cycle", input: "out", output: "in",
+			expected: conveyer.ErrCycle, channels: []string{"in", "out", "in"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			conv := conveyer.New(10)
+			_, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+			require.NoError(t, err)
+
+			_, errCh := runInBackground(t, conv)
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			require.NoError(t, conv.Send("in", "a"))
+
+			value, err := conv.RecvContext(ctx, "out")
+			require.NoError(t, err)
+			assert.Equal(t, "decorated: a", value)
+
+			handle, err := conv.RegisterDecorator(prefixWith("new: "), test.input, test.output)
+			require.ErrorIs(t, err, test.expected)
+			assert.Nil(t, handle)
+
+			var validationErr *conveyer.ValidationError
+
+			require.ErrorAs(t, err, &validationErr)
+			assert.Equal(t, test.channels, validationErr.Channels)
+
+			_, err = conv.Stop(ctx)
+			require.NoError(t, err)
+			require.NoError(t, <-errCh)
+		})
+	}
+}
+
+func TestReconfigure_Channels(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	handle, err := conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, conv.AddChannel("in"), conveyer.ErrChannelExists)
+	require.NoError(t, conv.AddChannel("side", conveyer.WithCapacity(3)))
+	require.ErrorIs(t, conv.RemoveChannel("in"), conveyer.ErrChannelInUse)
+	require.ErrorIs(t, conv.RemoveChannel("missing"), conveyer.ErrChanNotFound)
+
+	require.NoError(t, conv.Send("side", "kept"))
+	require.ErrorIs(t, conv.RemoveChannel("side"), conveyer.ErrChannelNotEmpty)
+
+	_, err = conv.Recv("side")
+	require.NoError(t, err)
+	require.NoError(t, conv.RemoveChannel("side"))
+	require.ErrorIs(t, conv.Send("side", "late"), conveyer.ErrChanNotFound)
+
+	require.NoError(t, handle.Detach(context.Background()))
+	require.NoError(t, conv.RemoveChannel("in"))
+	assert.Len(t, conv.Stats().Channels, 1)
+}
+
+func TestReconfigure_RemoveChannelWithBlockedSender(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(10)
+	require.NoError(t, conv.AddChannel("side", conveyer.WithCapacity(1)))
+	require.NoError(t, conv.Send("side", "a"))
+
+	sent := make(chan error, 1)
+
+	go func() {
+		sent <- conv.Send("side", "b")
+	}()
+
+	removed := make(chan error, 1)
+
+	go func() {
+		removed <- conv.RemoveChannel("side")
+	}()
+
+	select {
+	case err := <-removed:
+		require.ErrorIs(t, err, conveyer.ErrChannelNotEmpty)
+	case <-time.After(time.Second):
+		require.FailNow(t, "RemoveChannel waited for a blocked sender")
+	}
+
+	for _, want := range []string{"a", "b"} {
+		value, err := conv.Recv("side")
+		require.NoError(t, err)
+		assert.Equal(t, want, value)
+	}
+
+	require.NoError(t, <-sent)
+	require.NoError(t, conv.RemoveChannel("side"))
+}
@@ -0,0 +1,21 @@
+package conveyer
+
+import (
+	"errors"
+	"iter"
+)
+
+func (c *Typed[T]) Stream(pipeName string) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			value, err := c.Recv(pipeName)
+			if errors.Is(err, ErrChannelClosed) {
+				return
+			}
+
+			if !yield(value, err) || err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,566 @@
+package conveyer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	ErrTooManyRestarts  = errors.New("handler restarted too many times")
+	ErrNothingToDiscard = errors.New("handler failed without a message in flight")
+)
+
+type inFlight[T any] struct {
+	input    int
+	envelope Envelope[T]
+	set      bool
+}
+
+// supervise runs the handler on private channels so that a failed attempt can be
+// restarted without closing the shared streams it reads from and writes to.
+func (c *Typed[T]) supervise(
+	ctx context.Context,
+	registered stage[T],
+	inputs []chan Envelope[T],
+	outputs []chan Envelope[T],
+) error {
+	pending := make([][]Envelope[T], len(inputs))
+	delivered := make([]inFlight[T], len(inputs))
+	tracer := c.newStageTracer(registered)
+
+	for index, name := range registered.inputs {
+		pending[index] = c.takeHandOff(name)
+	}
+
+	defer func() {
+		tracer.exitAll(nil)
+
+		if registered.control.detached() {
+			for index, name := range registered.inputs {
+				c.handOff(name, pending[index])
+			}
+
+			return
+		}
+
+		stranded := 0
+
+		for _, queued := range pending {
+			stranded += len(queued)
+		}
+
+		registered.metrics.stranded.Store(int64(stranded))
+
+		for _, output := range outputs {
+			close(output)
+		}
+	}()
+
+	policy := registered.config.policy
+	restarts := 0
+
+	var backoff time.Duration
+
+	for {
+		var current inFlight[T]
+
+		completed, err := c.attempt(ctx, registered, inputs, outputs, pending, delivered, &current, tracer)
+
+		// MaxRestarts limits consecutive failures: a handler that got through a
+		// message since the last restart starts counting afresh.
+		if completed > 0 {
+			restarts, backoff = 0, 0
+		}
+
+		if err != nil {
+			registered.metrics.errors.Add(1)
+
+			if current.set {
+				tracer.exit(current.input, err)
+			}
+		}
+
+		if ctx.Err() != nil {
+			return err
+		}
+
+		if err == nil {
+			tracer.exitAll(nil)
+
+			return c.acknowledgeDelivered(registered, delivered)
+		}
+
+		if registered.control.detached() {
+			return err
+		}
+
+		switch policy.Kind {
+		case PolicyFailFast:
+			return err
+
+		case PolicyRestart:
+			restarts++
+
+			if policy.MaxRestarts > 0 && restarts > policy.MaxRestarts {
+				return fmt.Errorf("%w: %w", ErrTooManyRestarts, err)
+			}
+
+			if current.set {
+				pending[current.input] = append([]Envelope[T]{current.envelope}, pending[current.input]...)
+				delivered[current.input] = inFlight[T]{}
+			}
+
+			backoff = policy.nextBackoff(backoff)
+
+			if !sleepContext(ctx, backoff) {
+				return err
+			}
+
+		case PolicySkip:
+			if !current.set {
+				return fmt.Errorf("%w: %w", ErrNothingToDiscard, err)
+			}
+
+			if err := c.discard(registered, delivered, current); err != nil {
+				return err
+			}
+
+		case PolicyDeadLetter:
+			if !current.set {
+				return fmt.Errorf("%w: %w", ErrNothingToDiscard, err)
+			}
+
+			letters := c.ensureDeadLetter(policy.DeadLetterChannel)
+			letter := DeadLetter[T]{
+				Envelope: current.envelope,
+				Input:    registered.inputs[current.input],
+				Err:      err,
+			}
+
+			select {
+			case letters <- letter:
+			case <-ctx.Done():
+				return nil
+			}
+
+			if err := c.discard(registered, delivered, current); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (c *Typed[T]) discard(registered stage[T], delivered []inFlight[T], current inFlight[T]) error {
+	delivered[current.input] = inFlight[T]{}
+
+	return c.acknowledge(registered.inputs[current.input], current.envelope.ID)
+}
+
+func (c *Typed[T]) acknowledgeDelivered(registered stage[T], delivered []inFlight[T]) error {
+	for index, message := range delivered {
+		if !message.set {
+			continue
+		}
+
+		if err := c.acknowledge(registered.inputs[index], message.envelope.ID); err != nil {
+			return err
+		}
+
+		delivered[index] = inFlight[T]{}
+	}
+
+	return nil
+}
+
+func (c *Typed[T]) attempt(
+	ctx context.Context,
+	registered stage[T],
+	inputs []chan Envelope[T],
+	outputs []chan Envelope[T],
+	pending [][]Envelope[T],
+	delivered []inFlight[T],
+	current *inFlight[T],
+	tracer *stageTracer,
+) (int, error) {
+	handlerCtx, cancelHandler := context.WithCancel(ctx)
+	defer cancelHandler()
+
+	handlerDone := make(chan struct{})
+	privateInputs := make([]chan T, len(inputs))
+	privateOutputs := make([]chan T, len(outputs))
+
+	for index := range privateInputs {
+		privateInputs[index] = make(chan T)
+	}
+
+	for index := range privateOutputs {
+		privateOutputs[index] = make(chan T)
+	}
+
+	var err error
+
+	go func() {
+		defer close(handlerDone)
+
+		err = invokeRecovered(registered.name, func() error {
+			return registered.invoke(handlerCtx, privateInputs, privateOutputs)
+		})
+	}()
+
+	bridge := &bridge[T]{
+		conveyer:       c,
+		stage:          registered,
+		handlerDone:    handlerDone,
+		inputs:         inputs,
+		outputs:        outputs,
+		privateInputs:  privateInputs,
+		privateOutputs: privateOutputs,
+		pending:        pending,
+		delivered:      delivered,
+		current:        current,
+		tracer:         tracer,
+		sourceClosed:   make([]bool, len(inputs)),
+		inputClosed:    make([]bool, len(inputs)),
+		outputClosed:   make([]bool, len(outputs)),
+		cases:          nil,
+		actions:        nil,
+		detaching:      false,
+		completed:      0,
+		err:            nil,
+	}
+	bridge.run(ctx)
+
+	// A bridge that failed to persist or acknowledge stops serving the handler,
+	// which is then cancelled so it cannot block on its private channels.
+	if bridge.err != nil {
+		cancelHandler()
+	}
+
+	<-handlerDone
+
+	if bridge.err != nil {
+		return bridge.completed, bridge.err
+	}
+
+	return bridge.completed, err
+}
+
+// bridge moves messages between the shared streams and the handler's private
+// channels from a single goroutine. Because every hand-off is observed in the
+// order the handler performs it, each output can be attributed to the message
+// it was produced from.
+type bridge[T any] struct {
+	conveyer       *Typed[T]
+	stage          stage[T]
+	handlerDone    chan struct{}
+	inputs         []chan Envelope[T]
+	outputs        []chan Envelope[T]
+	privateInputs  []chan T
+	privateOutputs []chan T
+	pending        [][]Envelope[T]
+	delivered      []inFlight[T]
+	current        *inFlight[T]
+	tracer         *stageTracer
+	sourceClosed   []bool
+	inputClosed    []bool
+	outputClosed   []bool
+	cases          []reflect.SelectCase
+	actions        []bridgeCase
+	detaching      bool
+	completed      int
+	err            error
+}
+
+type bridgeAction int
+
+const (
+	actionHandlerDone bridgeAction = iota
+	actionCancelled
+	actionDetach
+	actionReceive
+	actionDeliver
+	actionEmit
+)
+
+type bridgeCase struct {
+	action bridgeAction
+	index  int
+}
+
+// bridgeEvent is what one select of the bridge came up with: a message received
+// from a shared input, or a payload emitted by the handler.
+type bridgeEvent[T any] struct {
+	bridgeCase
+
+	envelope Envelope[T]
+	payload  T
+	ok       bool
+}
+
+func (b *bridge[T]) run(ctx context.Context) {
+	for {
+		var event bridgeEvent[T]
+
+		if len(b.inputs) == 1 && len(b.outputs) == 1 {
+			event = b.selectSingle(ctx)
+		} else {
+			event = b.selectAny(ctx)
+		}
+
+		switch event.action {
+		case actionHandlerDone, actionCancelled:
+			return
+
+		case actionDetach:
+			b.detaching = true
+
+			for index, closed := range b.inputClosed {
+				if !closed {
+					close(b.privateInputs[index])
+					b.inputClosed[index] = true
+				}
+			}
+
+		case actionReceive:
+			if !event.ok {
+				b.sourceClosed[event.index] = true
+
+				continue
+			}
+
+			b.pending[event.index] = append(b.pending[event.index], event.envelope)
+
+		case actionDeliver:
+			envelope := b.pending[event.index][0]
+			b.pending[event.index] = b.pending[event.index][1:]
+			*b.current = inFlight[T]{input: event.index, envelope: envelope, set: true}
+			b.stage.metrics.received()
+
+			// Asking for the next message on an input means the handler is done
+			// with the previous one, so that one can be acknowledged.
+			if !b.advance(event.index) {
+				return
+			}
+
+			b.tracer.enter(event.index, envelope.ID)
+
+		case actionEmit:
+			if !event.ok {
+				b.outputClosed[event.index] = true
+
+				continue
+			}
+
+			if !b.forward(ctx, event.index, event.payload) {
+				return
+			}
+		}
+	}
+}
+
+// closeExhausted closes the private input of a shared input that was closed
+// and has nothing left to deliver, and reports whether it is closed.
+func (b *bridge[T]) closeExhausted(index int) bool {
+	if !b.inputClosed[index] && len(b.pending[index]) == 0 && b.sourceClosed[index] {
+		close(b.privateInputs[index])
+		b.inputClosed[index] = true
+	}
+
+	return b.inputClosed[index]
+}
+
+// selectSingle serves decorators, the most common stage, with a plain select:
+// it neither allocates nor goes through reflection. Disabled cases use nil
+// channels, which are never ready.
+func (b *bridge[T]) selectSingle(ctx context.Context) bridgeEvent[T] {
+	var (
+		detach  <-chan struct{}
+		source  chan Envelope[T]
+		deliver chan T
+		next    T
+		emit    chan T
+	)
+
+	if !b.detaching {
+		detach = b.stage.control.detach
+	}
+
+	if !b.closeExhausted(0) {
+		if len(b.pending[0]) > 0 {
+			deliver, next = b.privateInputs[0], b.pending[0][0].Payload
+		} else {
+			source = b.inputs[0]
+		}
+	}
+
+	if !b.outputClosed[0] {
+		emit = b.privateOutputs[0]
+	}
+
+	var event bridgeEvent[T]
+
+	select {
+	case <-b.handlerDone:
+		event.action = actionHandlerDone
+	case <-ctx.Done():
+		event.action = actionCancelled
+	case <-detach:
+		event.action = actionDetach
+	case event.envelope, event.ok = <-source:
+		event.action = actionReceive
+	case deliver <- next:
+		event.action = actionDeliver
+	case event.payload, event.ok = <-emit:
+		event.action = actionEmit
+	}
+
+	return event
+}
+
+// selectAny serves stages with several inputs or outputs through reflect.Select.
+func (b *bridge[T]) selectAny(ctx context.Context) bridgeEvent[T] {
+	b.buildCases(ctx)
+
+	chosen, received, ok := reflect.Select(b.cases)
+	event := bridgeEvent[T]{bridgeCase: b.actions[chosen], envelope: Envelope[T]{}, payload: *new(T), ok: ok}
+
+	if !received.IsValid() || !ok {
+		return event
+	}
+
+	switch event.action {
+	case actionReceive:
+		event.envelope, _ = received.Interface().(Envelope[T])
+	case actionEmit:
+		event.payload, _ = received.Interface().(T)
+	case actionHandlerDone, actionCancelled, actionDetach, actionDeliver:
+	}
+
+	return event
+}
+
+func (b *bridge[T]) buildCases(ctx context.Context) {
+	b.cases = append(b.cases[:0], recvCase(b.handlerDone), recvCase(ctx.Done()))
+	b.actions = append(b.actions[:0],
+		bridgeCase{action: actionHandlerDone, index: 0},
+		bridgeCase{action: actionCancelled, index: 0})
+
+	if !b.detaching {
+		b.cases = append(b.cases, recvCase(b.stage.control.detach))
+		b.actions = append(b.actions, bridgeCase{action: actionDetach, index: 0})
+	}
+
+	for index := range b.inputs {
+		if b.closeExhausted(index) {
+			continue
+		}
+
+		if len(b.pending[index]) > 0 {
+			payload := b.pending[index][0].Payload
+			b.cases = append(b.cases, reflect.SelectCase{
+				Dir:  reflect.SelectSend,
+				Chan: reflect.ValueOf(b.privateInputs[index]),
+				Send: reflect.ValueOf(&payload).Elem(),
+			})
+			b.actions = append(b.actions, bridgeCase{action: actionDeliver, index: index})
+
+			continue
+		}
+
+		b.cases = append(b.cases, recvCase(b.inputs[index]))
+		b.actions = append(b.actions, bridgeCase{action: actionReceive, index: index})
+	}
+
+	for index := range b.outputs {
+		if !b.outputClosed[index] {
+			b.cases = append(b.cases, recvCase(b.privateOutputs[index]))
+			b.actions = append(b.actions, bridgeCase{action: actionEmit, index: index})
+		}
+	}
+}
+
+func (b *bridge[T]) advance(input int) bool {
+	previous := b.delivered[input]
+	b.delivered[input] = *b.current
+
+	if !previous.set {
+		return true
+	}
+
+	b.completed++
+	b.err = b.conveyer.acknowledge(b.stage.inputs[input], previous.envelope.ID)
+
+	return b.err == nil
+}
+
+func (b *bridge[T]) forward(ctx context.Context, index int, payload T) bool {
+	envelope := b.attribute(payload)
+
+	b.stage.metrics.emitted()
+
+	name := b.stage.outputs[index]
+	b.tracer.emit(envelope.ID, name)
+
+	if b.err = b.conveyer.persist(name, envelope); b.err != nil {
+		return false
+	}
+
+	err := b.conveyer.push(ctx, name, b.outputs[index], envelope, nil)
+	if err != nil && ctx.Err() == nil {
+		b.err = err
+	}
+
+	return err == nil
+}
+
+// attribute derives an output from the message in flight on the input it came
+// from. A multiplexer has several inputs in flight at once and its outputs do
+// not say which one they came from: they go to the in-flight message with an
+// equal payload, preferring the one received last, which also takes outputs
+// that match nothing. Equal payloads in flight at once can't be told apart.
+func (b *bridge[T]) attribute(payload T) Envelope[T] {
+	source := *b.current
+
+	switch {
+	case len(b.delivered) == 1:
+		source = b.delivered[0]
+	case source.set && reflect.DeepEqual(source.envelope.Payload, payload):
+	default:
+		for _, message := range b.delivered {
+			if message.set && reflect.DeepEqual(message.envelope.Payload, payload) {
+				source = message
+
+				break
+			}
+		}
+	}
+
+	if !source.set {
+		return NewEnvelope(payload)
+	}
+
+	return source.envelope.derive(payload)
+}
+
+func recvCase[C any](channel <-chan C) reflect.SelectCase {
+	return reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(channel),
+		Send: reflect.Value{},
+	}
+}
+
+func sleepContext(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,124 @@
+package conveyer
+
+import "maps"
+
+// channelTable is an immutable snapshot of the channels and their state. It is
+// republished under c.mu whenever a channel is added or removed, so the send
+// and receive paths look channels up without taking a lock.
+type channelTable[T any] struct {
+	streams map[string]chan Envelope[T]
+	states  map[string]*channelState[T]
+	durable map[string]*durableLog[T]
+}
+
+// publishLocked replaces the snapshot. It is called with c.mu held.
+func (c *Typed[T]) publishLocked() {
+	c.table.Store(&channelTable[T]{
+		streams: maps.Clone(c.streams),
+		states:  maps.Clone(c.states),
+		durable: maps.Clone(c.durable),
+	})
+}
+
+func (c *Typed[T]) channels() *channelTable[T] {
+	return c.table.Load()
+}
+
+// bindLocked resolves channel names once when a stage starts, so handlers hold
+// their channels directly. It is called with c.mu held.
+func (c *Typed[T]) bindLocked(names []string) []chan Envelope[T] {
+	channels := make([]chan Envelope[T], len(names))
+
+	for index, name := range names {
+		channels[index] = c.streams[name]
+	}
+
+	return channels
+}
+
+// enterSend admits a send to a channel without taking a lock. Until the sender
+// calls leave, Drain, RemoveChannel and Reset wait before closing the channel.
+func (c *Typed[T]) enterSend(name string) (chan Envelope[T], *channelState[T], error) {
+	for {
+		table := c.channels()
+
+		channel, exists := table.streams[name]
+		if !exists {
+			return nil, nil, ErrChanNotFound
+		}
+
+		state := table.states[name]
+
+		if state.enter() {
+			if err := c.admitSend(); err != nil {
+				state.leave()
+
+				return nil, nil, err
+			}
+
+			return channel, state, nil
+		}
+
+		// The channel is being closed: by Drain, which admitSend reports, or by
+		// RemoveChannel or Reset, which hold c.mu until they have republished the
+		// table or unsealed the channel.
+		if err := c.admitSend(); err != nil {
+			return nil, nil, err
+		}
+
+		c.mu.RLock()
+		c.mu.RUnlock() //nolint:staticcheck // Waits for the closer to finish.
+	}
+}
+
+func (s *channelState[T]) enter() bool {
+	s.senders.Add(1)
+
+	if s.sealed.Load() {
+		s.leave()
+
+		return false
+	}
+
+	return true
+}
+
+func (s *channelState[T]) leave() {
+	if s.senders.Add(-1) == 0 && s.sealed.Load() {
+		s.mu.Lock()
+		s.idle.Broadcast()
+		s.mu.Unlock()
+	}
+}
+
+// seal turns new senders away and waits for those already admitted. Senders
+// blocked on a full channel have to be released first, by the drain signal or
+// by abort. It must not be called with s.mu held.
+func (s *channelState[T]) seal() {
+	s.sealed.Store(true)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.senders.Load() > 0 {
+		s.idle.Wait()
+	}
+}
+
+// trySeal turns new senders away unless some are already admitted, in which
+// case it leaves the channel open and reports false.
+func (s *channelState[T]) trySeal() bool {
+	s.sealed.Store(true)
+
+	if s.senders.Load() > 0 {
+		s.unseal()
+
+		return false
+	}
+
+	return true
+}
+
+func (s *channelState[T]) unseal() {
+	s.sealed.Store(false)
+}
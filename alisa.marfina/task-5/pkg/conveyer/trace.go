@@ -0,0 +1,208 @@
+package conveyer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+	"time"
+)
+
+type Outcome string
+
+const (
+	OutcomeForwarded Outcome = "forwarded"
+	OutcomeFiltered  Outcome = "filtered"
+	OutcomeErrored   Outcome = "errored"
+)
+
+// Span records one message passing through one stage: from the moment it is
+// handed to the handler until the handler asks for the next message on the same
+// input, finishes or fails.
+type Span struct {
+	MessageID string    `json:"message-id"`
+	Stage     string    `json:"stage"`
+	Input     string    `json:"input"`
+	Outputs   []string  `json:"outputs,omitempty"`
+	Enter     time.Time `json:"enter"`
+	Exit      time.Time `json:"exit"`
+	Outcome   Outcome   `json:"outcome"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// SpanExporter receives finished spans. It is called from the stages' own
+// goroutines, so it must be safe for concurrent use and should not block.
+type SpanExporter interface {
+	ExportSpan(span Span)
+}
+
+func WithTracing(exporter SpanExporter) Option {
+	return func(opts *options) {
+		opts.tracing = exporter
+	}
+}
+
+// stageTracer keeps the open span of every input of a stage across attempts.
+type stageTracer struct {
+	exporter SpanExporter
+	stage    string
+	inputs   []string
+	open     []*Span
+}
+
+func (c *Typed[T]) newStageTracer(registered stage[T]) *stageTracer {
+	return &stageTracer{
+		exporter: c.options.tracing,
+		stage:    registered.name,
+		inputs:   registered.inputs,
+		open:     make([]*Span, len(registered.inputs)),
+	}
+}
+
+func (t *stageTracer) enter(input int, messageID string) {
+	if t.exporter == nil {
+		return
+	}
+
+	t.exit(input, nil)
+	t.open[input] = &Span{
+		MessageID: messageID,
+		Stage:     t.stage,
+		Input:     t.inputs[input],
+		Outputs:   nil,
+		Enter:     time.Now(),
+		Exit:      time.Time{},
+		Outcome:   "",
+		Error:     "",
+	}
+}
+
+func (t *stageTracer) emit(messageID string, output string) {
+	if t.exporter == nil {
+		return
+	}
+
+	for _, span := range t.open {
+		if span != nil && span.MessageID == messageID {
+			span.Outputs = append(span.Outputs, output)
+
+			return
+		}
+	}
+}
+
+func (t *stageTracer) exit(input int, err error) {
+	span := t.open[input]
+	if span == nil {
+		return
+	}
+
+	t.open[input] = nil
+	span.Exit = time.Now()
+
+	switch {
+	case err != nil:
+		span.Outcome, span.Error = OutcomeErrored, err.Error()
+	case len(span.Outputs) > 0:
+		span.Outcome = OutcomeForwarded
+	default:
+		span.Outcome = OutcomeFiltered
+	}
+
+	t.exporter.ExportSpan(*span)
+}
+
+func (t *stageTracer) exitAll(err error) {
+	for input := range t.open {
+		t.exit(input, err)
+	}
+}
+
+// MemoryExporter keeps spans in memory, mostly for tests.
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []Span
+}
+
+func NewMemoryExporter() *MemoryExporter {
+	return &MemoryExporter{mu: sync.Mutex{}, spans: make([]Span, 0)}
+}
+
+func (e *MemoryExporter) ExportSpan(span Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.spans = append(e.spans, span)
+}
+
+func (e *MemoryExporter) Spans() []Span {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return slices.Clone(e.spans)
+}
+
+// JSONLinesExporter writes every span as one JSON object per line. Write errors
+// do not disturb the pipeline; the first one is reported by Err and Close.
+type JSONLinesExporter struct {
+	mu      sync.Mutex
+	encoder *json.Encoder
+	closer  io.Closer
+	err     error
+}
+
+func NewJSONLinesExporter(writer io.Writer) *JSONLinesExporter {
+	return &JSONLinesExporter{mu: sync.Mutex{}, encoder: json.NewEncoder(writer), closer: nil, err: nil}
+}
+
+// CreateJSONLinesFile creates or truncates the file at path; Close closes it.
+func CreateJSONLinesFile(path string) (*JSONLinesExporter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create trace file: %w", err)
+	}
+
+	exporter := NewJSONLinesExporter(file)
+	exporter.closer = file
+
+	return exporter, nil
+}
+
+func (e *JSONLinesExporter) ExportSpan(span Span) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.err != nil {
+		return
+	}
+
+	if err := e.encoder.Encode(span); err != nil {
+		e.err = fmt.Errorf("write span: %w", err)
+	}
+}
+
+func (e *JSONLinesExporter) Err() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.err
+}
+
+func (e *JSONLinesExporter) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closer == nil {
+		return e.err
+	}
+
+	if err := e.closer.Close(); err != nil && e.err == nil {
+		e.err = fmt.Errorf("close trace file: %w", err)
+	}
+
+	e.closer = nil
+
+	return e.err
+}
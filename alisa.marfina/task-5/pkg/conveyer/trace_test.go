@@ -0,0 +1,132 @@
+package conveyer_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func spansOf(spans []conveyer.Span, messageID string) map[string]conveyer.Span {
+	byStage := make(map[string]conveyer.Span)
+
+	for _, span := range spans {
+		if span.MessageID == messageID {
+			byStage[span.Stage] = span
+		}
+	}
+
+	return byStage
+}
+
+func TestTracing_ShowsWhereMessagesGo(t *testing.T) {
+	t.Parallel()
+
+	exporter := conveyer.NewMemoryExporter()
+
+	conv := conveyer.New(10, conveyer.WithTracing(exporter))
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "decorated",
+		conveyer.WithName("decorate"), conveyer.WithErrorPolicy(conveyer.SkipMessage()))
+	conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"decorated"}, "out", conveyer.WithName("merge"))
+
+	_, errCh := runInBackground(t, conv)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	messages := make(map[string]conveyer.Envelope[string])
+
+	for _, payload := range []string{"kept", "no multiplexer", "no decorator"} {
+		envelope := conveyer.NewEnvelope(payload)
+		messages[payload] = envelope
+
+		require.NoError(t, conv.SendEnvelope(ctx, "in", envelope))
+	}
+
+	value, err := conv.RecvContext(ctx, "out")
+	require.NoError(t, err)
+	assert.Equal(t, "decorated: kept", value)
+
+	_, err = conv.Stop(ctx)
+	require.NoError(t, err)
+	require.NoError(t, <-errCh)
+
+	spans := exporter.Spans()
+
+	kept := spansOf(spans, messages["kept"].ID)
+	require.Contains(t, kept, "decorate")
+	require.Contains(t, kept, "merge")
+	assert.Equal(t, conveyer.OutcomeForwarded, kept["decorate"].Outcome)
+	assert.Equal(t, "in", kept["decorate"].Input)
+	assert.Equal(t, []string{"decorated"}, kept["decorate"].Outputs)
+	assert.Equal(t, []string{"out"}, kept["merge"].Outputs)
+	assert.False(t, kept["merge"].Exit.Before(kept["merge"].Enter))
+
+	filtered := spansOf(spans, messages["no multiplexer"].ID)
+	assert.Equal(t, conveyer.OutcomeForwarded, filtered["decorate"].Outcome)
+	assert.Equal(t, conveyer.OutcomeFiltered, filtered["merge"].Outcome)
+	assert.Empty(t, filtered["merge"].Outputs)
+
+	errored := spansOf(spans, messages["no decorator"].ID)
+	require.Len(t, errored, 1)
+	assert.Equal(t, conveyer.OutcomeErrored, errored["decorate"].Outcome)
+	assert.Contains(t, errored["decorate"].Error, handlers.ErrCannotBeDecorated.Error())
+}
+
+func TestJSONLinesExporter(t *testing.T) {
+	t.Parallel()
+
+	span := conveyer.Span{
+		MessageID: "id",
+		Stage:     "stage",
+		Input:     "in",
+		Outputs:   []string{"out"},
+		Enter:     time.Unix(0, 0).UTC(),
+		Exit:      time.Unix(1, 0).UTC(),
+		Outcome:   conveyer.OutcomeForwarded,
+		Error:     "",
+	}
+
+	var buffer bytes.Buffer
+
+	exporter := conveyer.NewJSONLinesExporter(&buffer)
+	exporter.ExportSpan(span)
+	exporter.ExportSpan(span)
+	require.NoError(t, exporter.Close())
+
+	scanner := bufio.NewScanner(&buffer)
+	lines := 0
+
+	for scanner.Scan() {
+		var decoded conveyer.Span
+
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &decoded))
+		assert.Equal(t, span, decoded)
+
+		lines++
+	}
+
+	assert.Equal(t, 2, lines)
+
+	path := filepath.Join(t.TempDir(), "trace.jsonl")
+
+	file, err := conveyer.CreateJSONLinesFile(path)
+	require.NoError(t, err)
+
+	file.ExportSpan(span)
+	require.NoError(t, file.Close())
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `"outcome":"forwarded"`)
+}
@@ -0,0 +1,219 @@
+package conveyer
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrCycle           = errors.New("cycle between channels")
+	ErrMultipleWriters = errors.New("channel has multiple writers")
+	ErrNoReader        = errors.New("channel has no reader")
+	ErrNoWriter        = errors.New("channel has no writer")
+)
+
+type ValidationError struct {
+	Err      error
+	Channels []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%v: %s", e.Err, strings.Join(e.Channels, ", "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (c *Typed[T]) DeclareInputs(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, name := range names {
+		c.sources[name] = struct{}{}
+		c.ensureChanLocked(name)
+	}
+}
+
+func (c *Typed[T]) DeclareOutputs(names ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, name := range names {
+		c.sinks[name] = struct{}{}
+		c.ensureChanLocked(name)
+	}
+}
+
+func (c *Typed[T]) Validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	writers := make(map[string]int)
+	readers := make(map[string]int)
+
+	for _, registered := range c.stages {
+		for _, name := range registered.outputs {
+			writers[name]++
+		}
+
+		for _, name := range registered.inputs {
+			readers[name]++
+		}
+	}
+
+	for name := range c.sources {
+		writers[name]++
+	}
+
+	var (
+		errs            = slices.Clone(c.durableErrs)
+		multipleWriters []string
+	)
+
+	for _, name := range sortedKeys(writers) {
+		if writers[name] > 1 {
+			multipleWriters = append(multipleWriters, name)
+		}
+	}
+
+	if len(multipleWriters) > 0 {
+		errs = append(errs, &ValidationError{Err: ErrMultipleWriters, Channels: multipleWriters})
+	}
+
+	if len(c.sources) > 0 || len(c.sinks) > 0 {
+		errs = append(errs, c.danglingErrors(writers, readers)...)
+	}
+
+	if cycle := findCycle(c.stages); cycle != nil {
+		errs = append(errs, &ValidationError{Err: ErrCycle, Channels: cycle})
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Typed[T]) danglingErrors(writers, readers map[string]int) []error {
+	var noWriter, noReader []string
+
+	for _, name := range sortedKeys(c.streams) {
+		if _, isSink := c.sinks[name]; !isSink && readers[name] == 0 {
+			noReader = append(noReader, name)
+		}
+
+		if writers[name] == 0 {
+			noWriter = append(noWriter, name)
+		}
+	}
+
+	var errs []error
+
+	if len(noWriter) > 0 {
+		errs = append(errs, &ValidationError{Err: ErrNoWriter, Channels: noWriter})
+	}
+
+	if len(noReader) > 0 {
+		errs = append(errs, &ValidationError{Err: ErrNoReader, Channels: noReader})
+	}
+
+	return errs
+}
+
+// checkAddedLocked checks a stage added to a running conveyer against the live
+// graph: it may not write to a channel that already has a writer, nor close a
+// cycle. Detached stages no longer count. It is called with c.mu held.
+func (c *Typed[T]) checkAddedLocked(added stage[T]) error {
+	live := slices.DeleteFunc(slices.Clone(c.stages), func(registered stage[T]) bool {
+		return registered.control.detached()
+	})
+
+	var shared []string
+
+	for _, name := range added.outputs {
+		_, isSource := c.sources[name]
+		written := slices.ContainsFunc(live, func(registered stage[T]) bool {
+			return slices.Contains(registered.outputs, name)
+		})
+
+		if isSource || written {
+			shared = append(shared, name)
+		}
+	}
+
+	if len(shared) > 0 {
+		return &ValidationError{Err: ErrMultipleWriters, Channels: shared}
+	}
+
+	if cycle := findCycle(append(live, added)); cycle != nil {
+		return &ValidationError{Err: ErrCycle, Channels: cycle}
+	}
+
+	return nil
+}
+
+func findCycle[T any](stages []stage[T]) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+
+	next := make(map[string][]string)
+
+	for _, registered := range stages {
+		for _, input := range registered.inputs {
+			next[input] = append(next[input], registered.outputs...)
+		}
+	}
+
+	state := make(map[string]int)
+	path := make([]string, 0)
+
+	var visit func(name string) []string
+
+	visit = func(name string) []string {
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, following := range next[name] {
+			switch state[following] {
+			case visiting:
+				start := slices.Index(path, following)
+
+				return append(slices.Clone(path[start:]), following)
+			case unvisited:
+				if cycle := visit(following); cycle != nil {
+					return cycle
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = done
+
+		return nil
+	}
+
+	for _, name := range sortedKeys(next) {
+		if state[name] == unvisited {
+			if cycle := visit(name); cycle != nil {
+				return cycle
+			}
+		}
+	}
+
+	return nil
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		keys = append(keys, key)
+	}
+
+	slices.Sort(keys)
+
+	return keys
+}
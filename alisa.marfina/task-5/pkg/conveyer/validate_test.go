@@ -0,0 +1,114 @@
+package conveyer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func TestConveyer_Validate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name             string
+		setup            func(*conveyer.Conveyer)
+		expectedErr      error
+		expectedChannels []string
+	}{
+		{
+			name: "valid pipeline",
+			setup: func(conv *conveyer.Conveyer) {
+				conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "mid")
+				conv.RegisterSeparator(handlers.SeparatorFunc, "mid", []string{"a", "b"})
+			},
+		},
+		{
+			name: "two writers",
+			setup: func(conv *conveyer.Conveyer) {
+				conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in1", "out")
+				conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in2", "out")
+			},
+			expectedErr:      conveyer.ErrMultipleWriters,
+			expectedChannels: []string{"out"},
+		},
+		{
+			name: "cycle",
+			setup: func(conv *conveyer.Conveyer) {
+				conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "a", "b")
+				conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"b", "in"}, "a")
+			},
+			expectedErr:      conveyer.ErrCycle,
+			expectedChannels: []string{"a", "b", "a"},
+		},
+		{
+			name: "declared input with a writer stage",
+			setup: func(conv *conveyer.Conveyer) {
+				conv.DeclareInputs("in")
+				conv.DeclareOutputs("out")
+				conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "out", "in")
+			},
+			expectedErr:      conveyer.ErrMultipleWriters,
+			expectedChannels: []string{"in"},
+		},
+		{
+			name: "nobody reads",
+			setup: func(conv *conveyer.Conveyer) {
+				conv.DeclareInputs("in")
+				conv.DeclareOutputs("a")
+				conv.RegisterSeparator(handlers.SeparatorFunc, "in", []string{"a", "b"})
+			},
+			expectedErr:      conveyer.ErrNoReader,
+			expectedChannels: []string{"b"},
+		},
+		{
+			name: "nobody writes",
+			setup: func(conv *conveyer.Conveyer) {
+				conv.DeclareInputs("in")
+				conv.DeclareOutputs("out")
+				conv.RegisterMultiplexer(handlers.MultiplexerFunc, []string{"in", "orphan"}, "out")
+			},
+			expectedErr:      conveyer.ErrNoWriter,
+			expectedChannels: []string{"orphan"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			conv := conveyer.New(1)
+			testCase.setup(conv)
+
+			err := conv.Validate()
+			if testCase.expectedErr == nil {
+				require.NoError(t, err)
+
+				return
+			}
+
+			require.ErrorIs(t, err, testCase.expectedErr)
+
+			var validationErr *conveyer.ValidationError
+
+			require.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, testCase.expectedChannels, validationErr.Channels)
+		})
+	}
+}
+
+func TestConveyer_RunRejectsInvalidTopology(t *testing.T) {
+	t.Parallel()
+
+	conv := conveyer.New(1)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in1", "out")
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in2", "out")
+
+	err := conv.Run(context.Background())
+	require.ErrorIs(t, err, conveyer.ErrMultipleWriters)
+}
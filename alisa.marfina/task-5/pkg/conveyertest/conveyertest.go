@@ -0,0 +1,289 @@
+// Package conveyertest runs a conveyer inside a test: it feeds scripted inputs,
+// collects outputs with timeouts and reports goroutines left behind by Run.
+package conveyertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+)
+
+const (
+	DefaultTimeout = time.Second
+	leakLabel      = "conveyertest"
+	leakPoll       = 5 * time.Millisecond
+)
+
+var harnessIDs atomic.Uint64
+
+type Harness[T any] struct {
+	t        testing.TB
+	conv     *conveyer.Typed[T]
+	id       string
+	timeout  time.Duration
+	cancel   context.CancelFunc
+	runErr   chan error
+	finished bool
+	err      error
+}
+
+// New creates a harness around a fresh conveyer. The conveyer is stopped and
+// checked for leaked goroutines when the test ends, if the test did not call
+// Wait itself.
+func New[T any](t testing.TB, size int, opts ...conveyer.Option) *Harness[T] {
+	t.Helper()
+
+	harness := &Harness[T]{
+		t:        t,
+		conv:     conveyer.NewTyped[T](size, opts...),
+		id:       strconv.FormatUint(harnessIDs.Add(1), 10),
+		timeout:  DefaultTimeout,
+		cancel:   nil,
+		runErr:   nil,
+		finished: false,
+		err:      nil,
+	}
+
+	t.Cleanup(func() {
+		if harness.runErr != nil && !harness.finished {
+			harness.cancel()
+			_ = harness.Wait()
+		}
+	})
+
+	return harness
+}
+
+func (h *Harness[T]) Conveyer() *conveyer.Typed[T] {
+	return h.conv
+}
+
+// Timeout sets how long Feed, Collect and Wait may take before the test fails.
+func (h *Harness[T]) Timeout(timeout time.Duration) *Harness[T] {
+	h.timeout = timeout
+
+	return h
+}
+
+func (h *Harness[T]) Decorator(
+	handler func(context.Context, chan T, chan T) error,
+	input, output string,
+	opts ...conveyer.StageOption,
+) *Harness[T] {
+	h.t.Helper()
+
+	_, err := h.conv.RegisterDecorator(handler, input, output, opts...)
+	require.NoError(h.t, err)
+
+	return h
+}
+
+func (h *Harness[T]) Separator(
+	handler func(context.Context, chan T, []chan T) error,
+	input string,
+	outputs []string,
+	opts ...conveyer.StageOption,
+) *Harness[T] {
+	h.t.Helper()
+
+	_, err := h.conv.RegisterSeparator(handler, input, outputs, opts...)
+	require.NoError(h.t, err)
+
+	return h
+}
+
+func (h *Harness[T]) Multiplexer(
+	handler func(context.Context, []chan T, chan T) error,
+	inputs []string,
+	output string,
+	opts ...conveyer.StageOption,
+) *Harness[T] {
+	h.t.Helper()
+
+	_, err := h.conv.RegisterMultiplexer(handler, inputs, output, opts...)
+	require.NoError(h.t, err)
+
+	return h
+}
+
+// Start runs the conveyer in the background. Every goroutine started by Run is
+// labelled, so leaks are found even while other tests run in parallel.
+func (h *Harness[T]) Start() *Harness[T] {
+	h.t.Helper()
+
+	require.Nil(h.t, h.runErr, "conveyer already started")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel, h.runErr = cancel, make(chan error, 1)
+
+	go pprof.Do(ctx, pprof.Labels(leakLabel, h.id), func(ctx context.Context) {
+		h.runErr <- h.conv.Run(ctx)
+	})
+
+	return h
+}
+
+func (h *Harness[T]) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), h.timeout)
+}
+
+func (h *Harness[T]) Feed(channel string, values ...T) {
+	h.t.Helper()
+
+	ctx, cancel := h.context()
+	defer cancel()
+
+	for index, value := range values {
+		err := h.conv.SendContext(ctx, channel, value)
+		require.NoError(h.t, err, "feed %q: value %d", channel, index)
+	}
+}
+
+// Collect receives exactly count messages from a channel.
+func (h *Harness[T]) Collect(channel string, count int) []T {
+	h.t.Helper()
+
+	ctx, cancel := h.context()
+	defer cancel()
+
+	collected := make([]T, 0, count)
+
+	for len(collected) < count {
+		value, err := h.conv.RecvContext(ctx, channel)
+		require.NoError(h.t, err, "collect %q: got %d of %d messages: %v", channel, len(collected), count, collected)
+
+		collected = append(collected, value)
+	}
+
+	return collected
+}
+
+// CollectAll receives from a channel until it is closed, which usually follows
+// Drain.
+func (h *Harness[T]) CollectAll(channel string) []T {
+	h.t.Helper()
+
+	ctx, cancel := h.context()
+	defer cancel()
+
+	collected := make([]T, 0)
+
+	for {
+		value, err := h.conv.RecvContext(ctx, channel)
+		if errors.Is(err, conveyer.ErrChannelClosed) {
+			return collected
+		}
+
+		require.NoError(h.t, err, "collect %q until closed: got %v", channel, collected)
+
+		collected = append(collected, value)
+	}
+}
+
+func (h *Harness[T]) ExpectOrdered(channel string, expected ...T) {
+	h.t.Helper()
+
+	assert.Equal(h.t, expected, h.Collect(channel, len(expected)), "messages on %q", channel)
+}
+
+// ExpectUnordered compares the next len(expected) messages as a multiset, for
+// stages that do not preserve order.
+func (h *Harness[T]) ExpectUnordered(channel string, expected ...T) {
+	h.t.Helper()
+
+	assert.ElementsMatch(h.t, expected, h.Collect(channel, len(expected)), "messages on %q", channel)
+}
+
+func (h *Harness[T]) Drain() {
+	h.conv.Drain()
+}
+
+// Wait drains the conveyer, waits for Run to return and fails the test if any
+// goroutine started by Run outlives it. It returns the error from Run.
+func (h *Harness[T]) Wait() error {
+	h.t.Helper()
+
+	require.NotNil(h.t, h.runErr, "conveyer was not started")
+
+	if h.finished {
+		return h.err
+	}
+
+	h.finished = true
+	h.conv.Drain()
+
+	select {
+	case h.err = <-h.runErr:
+	case <-time.After(h.timeout):
+		h.cancel()
+		h.err = <-h.runErr
+		h.t.Errorf("conveyer did not stop within %v after Drain", h.timeout)
+	}
+
+	h.cancel()
+
+	if leaked, stacks := h.waitForGoroutines(); leaked > 0 {
+		h.t.Errorf("%d goroutine(s) started by Run are still running:\n%s", leaked, stacks)
+	}
+
+	return h.err
+}
+
+func (h *Harness[T]) waitForGoroutines() (int, string) {
+	deadline := time.Now().Add(h.timeout)
+
+	for {
+		leaked, stacks := labelledGoroutines(h.id)
+		if leaked == 0 || time.Now().After(deadline) {
+			return leaked, stacks
+		}
+
+		time.Sleep(leakPoll)
+	}
+}
+
+// labelledGoroutines counts goroutines carrying the harness label in the
+// goroutine profile, whose records are separated by blank lines and start with
+// "<count> @ <pcs>".
+func labelledGoroutines(id string) (int, string) {
+	var profile bytes.Buffer
+
+	_ = pprof.Lookup("goroutine").WriteTo(&profile, 1)
+
+	label := fmt.Sprintf("%q:%q", leakLabel, id)
+	count := 0
+
+	var stacks strings.Builder
+
+	for _, record := range strings.Split(profile.String(), "\n\n") {
+		if !strings.Contains(record, label) {
+			continue
+		}
+
+		header, _, _ := strings.Cut(record, " ")
+
+		goroutines, err := strconv.Atoi(header)
+		if err != nil {
+			continue
+		}
+
+		count += goroutines
+
+		stacks.WriteString(record)
+		stacks.WriteString("\n\n")
+	}
+
+	return count, stacks.String()
+}
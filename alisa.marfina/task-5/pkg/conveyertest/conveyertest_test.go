@@ -0,0 +1,99 @@
+package conveyertest_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyertest"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+// recorder captures failures so tests can check that the harness reports them.
+type recorder struct {
+	testing.TB
+
+	mu     sync.Mutex
+	errors []string
+}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.errors = append(r.errors, format)
+}
+
+func (r *recorder) failures() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.errors
+}
+
+func TestHarness_Pipeline(t *testing.T) {
+	t.Parallel()
+
+	harness := conveyertest.New[string](t, 10).
+		Decorator(handlers.PrefixDecoratorFunc, "in", "decorated").
+		Separator(handlers.SeparatorFunc, "decorated", []string{"left", "right"}).
+		Multiplexer(handlers.MultiplexerFunc, []string{"left", "right"}, "out").
+		Start()
+
+	harness.Feed("in", "a", "b", "c", "d")
+	harness.ExpectUnordered("out", "decorated: a", "decorated: b", "decorated: c", "decorated: d")
+
+	require.NoError(t, harness.Wait())
+	assert.Empty(t, harness.CollectAll("out"))
+}
+
+func TestHarness_ExpectOrdered(t *testing.T) {
+	t.Parallel()
+
+	upper := handlers.Decorator(func(value string) (string, error) {
+		return strings.ToUpper(value), nil
+	})
+
+	harness := conveyertest.New[string](t, 10).Decorator(upper, "in", "out").Start()
+
+	harness.Feed("in", "x", "y")
+	harness.ExpectOrdered("out", "X", "Y")
+
+	harness.Feed("in", "z")
+	harness.Drain()
+	assert.Equal(t, []string{"Z"}, harness.CollectAll("out"))
+	require.NoError(t, harness.Wait())
+}
+
+func TestHarness_DetectsLeakedGoroutine(t *testing.T) {
+	t.Parallel()
+
+	unblock := make(chan struct{})
+	t.Cleanup(func() { close(unblock) })
+
+	leaking := func(ctx context.Context, input, output chan string) error {
+		go func() {
+			<-unblock
+		}()
+
+		return handlers.SeparatorFunc(ctx, input, []chan string{output})
+	}
+
+	tb := &recorder{TB: t, mu: sync.Mutex{}, errors: nil}
+	harness := conveyertest.New[string](tb, 10).
+		Timeout(50*time.Millisecond).
+		Decorator(leaking, "in", "out").
+		Start()
+
+	harness.Feed("in", "a")
+	harness.ExpectOrdered("out", "a")
+
+	require.NoError(t, harness.Wait())
+	require.Len(t, tb.failures(), 1)
+	assert.Contains(t, tb.failures()[0], "still running")
+}
@@ -0,0 +1,283 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInjectedFault = errors.New("injected fault")
+	ErrInvalidChaos  = errors.New("invalid chaos configuration")
+)
+
+// Chaos configures the faults injected into a wrapped handler. Every rate is
+// the probability, per message, of that fault; at most one fault hits a
+// message, and the same Seed always yields the same faults.
+type Chaos struct {
+	Seed          uint64
+	Clock         Clock
+	DelayRate     float64
+	MaxDelay      time.Duration
+	DropRate      float64
+	DuplicateRate float64
+	ErrorRate     float64
+	PanicRate     float64
+}
+
+type fault int
+
+const (
+	faultNone fault = iota
+	faultDelay
+	faultDrop
+	faultDuplicate
+	faultError
+	faultPanic
+)
+
+func (c Chaos) validate() error {
+	for _, rate := range []float64{c.DelayRate, c.DropRate, c.DuplicateRate, c.ErrorRate, c.PanicRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%w: rate %v", ErrInvalidChaos, rate)
+		}
+	}
+
+	if c.DelayRate > 0 && c.MaxDelay <= 0 {
+		return fmt.Errorf("%w: max delay %v", ErrInvalidChaos, c.MaxDelay)
+	}
+
+	return nil
+}
+
+// injector is shared by every run of a wrapped handler, so a restarted stage
+// continues the fault sequence instead of replaying it.
+type injector[T any] struct {
+	config Chaos
+	clock  Clock
+	mu     sync.Mutex
+	rng    *rand.Rand
+}
+
+func newInjector[T any](config Chaos) *injector[T] {
+	clock := config.Clock
+	if clock == nil {
+		clock = SystemClock()
+	}
+
+	return &injector[T]{
+		config: config,
+		clock:  clock,
+		mu:     sync.Mutex{},
+		rng:    rand.New(rand.NewPCG(config.Seed, config.Seed)), //nolint:gosec
+	}
+}
+
+// draw picks the fault for one message, trying the most disruptive first.
+func (i *injector[T]) draw() (fault, time.Duration) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	candidates := []struct {
+		kind fault
+		rate float64
+	}{
+		{kind: faultPanic, rate: i.config.PanicRate},
+		{kind: faultError, rate: i.config.ErrorRate},
+		{kind: faultDrop, rate: i.config.DropRate},
+		{kind: faultDuplicate, rate: i.config.DuplicateRate},
+		{kind: faultDelay, rate: i.config.DelayRate},
+	}
+
+	for _, candidate := range candidates {
+		if i.rng.Float64() >= candidate.rate {
+			continue
+		}
+
+		if candidate.kind == faultDelay {
+			return faultDelay, time.Duration(i.rng.Int64N(int64(i.config.MaxDelay))) + 1
+		}
+
+		return candidate.kind, 0
+	}
+
+	return faultNone, 0
+}
+
+func ChaosDecorator[T any](
+	config Chaos,
+	handler func(context.Context, chan T, chan T) error,
+) func(context.Context, chan T, chan T) error {
+	inject := newInjector[T](config)
+
+	return func(ctx context.Context, input, output chan T) error {
+		return inject.run(ctx, []chan T{input}, []chan T{output}, func(ctx context.Context, inputs []chan T) error {
+			return handler(ctx, inputs[0], output)
+		})
+	}
+}
+
+func ChaosSeparator[T any](
+	config Chaos,
+	handler func(context.Context, chan T, []chan T) error,
+) func(context.Context, chan T, []chan T) error {
+	inject := newInjector[T](config)
+
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		return inject.run(ctx, []chan T{input}, outputs, func(ctx context.Context, inputs []chan T) error {
+			return handler(ctx, inputs[0], outputs)
+		})
+	}
+}
+
+func ChaosMultiplexer[T any](
+	config Chaos,
+	handler func(context.Context, []chan T, chan T) error,
+) func(context.Context, []chan T, chan T) error {
+	inject := newInjector[T](config)
+
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		return inject.run(ctx, inputs, []chan T{output}, func(ctx context.Context, inputs []chan T) error {
+			return handler(ctx, inputs, output)
+		})
+	}
+}
+
+type chaosMessage[T any] struct {
+	index  int
+	value  T
+	isOpen bool
+}
+
+// run feeds the handler through channels of its own and injects the faults on
+// the way. Errors and panics are raised in the calling goroutine, where the
+// conveyer can recover them; the handler is cancelled and awaited either way.
+func (i *injector[T]) run(
+	ctx context.Context,
+	inputs []chan T,
+	outputs []chan T,
+	handler func(context.Context, []chan T) error,
+) error {
+	if err := i.config.validate(); err != nil {
+		closeAll(outputs)
+
+		return err
+	}
+
+	handlerCtx, cancel := context.WithCancel(ctx)
+	feeds := make([]chan T, len(inputs))
+
+	for index := range feeds {
+		feeds[index] = make(chan T)
+	}
+
+	var handlerErr error
+
+	handlerDone := make(chan struct{})
+
+	go func() {
+		defer close(handlerDone)
+
+		handlerErr = handler(handlerCtx, feeds)
+	}()
+
+	defer func() {
+		cancel()
+		<-handlerDone
+	}()
+
+	merged := make(chan chaosMessage[T])
+
+	for index, input := range inputs {
+		go func() {
+			for {
+				select {
+				case value, isOpen := <-input:
+					select {
+					case merged <- chaosMessage[T]{index: index, value: value, isOpen: isOpen}:
+					case <-handlerCtx.Done():
+						return
+					}
+
+					if !isOpen {
+						return
+					}
+
+				case <-handlerCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	for open := len(inputs); open > 0; {
+		select {
+		case <-handlerDone:
+			return handlerErr
+
+		case <-ctx.Done():
+			<-handlerDone
+
+			return handlerErr
+
+		case message := <-merged:
+			if !message.isOpen {
+				close(feeds[message.index])
+
+				open--
+
+				continue
+			}
+
+			if err := i.inject(handlerCtx, message.value, feeds[message.index], handlerDone); err != nil {
+				return err
+			}
+		}
+	}
+
+	<-handlerDone
+
+	return handlerErr
+}
+
+func (i *injector[T]) inject(ctx context.Context, value T, feed chan T, handlerDone chan struct{}) error {
+	kind, delay := i.draw()
+	copies := 1
+
+	switch kind {
+	case faultPanic:
+		panic(ErrInjectedFault)
+	case faultError:
+		return ErrInjectedFault
+	case faultDrop:
+		return nil
+	case faultDuplicate:
+		copies = 2
+	case faultDelay:
+		timer := i.clock.NewTimer(delay)
+
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+
+			return nil
+		}
+	case faultNone:
+	}
+
+	for range copies {
+		select {
+		case feed <- value:
+		case <-handlerDone:
+			return nil
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
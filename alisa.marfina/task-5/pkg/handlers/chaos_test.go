@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func identity(value string) string {
+	return value
+}
+
+func TestChaosDecorator(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		config   handlers.Chaos
+		expected []string
+		err      error
+	}{
+		{name: "no faults", config: handlers.Chaos{}, expected: []string{"a", "b"}, err: nil},
+		{name: "drop", config: handlers.Chaos{DropRate: 1}, expected: []string{}, err: nil},
+		{name: "duplicate", config: handlers.Chaos{DuplicateRate: 1}, expected: []string{"a", "a", "b", "b"}, err: nil},
+		{name: "error", config: handlers.Chaos{ErrorRate: 1}, expected: []string{}, err: handlers.ErrInjectedFault},
+		{name: "invalid rate", config: handlers.Chaos{DropRate: 2}, expected: []string{}, err: handlers.ErrInvalidChaos},
+		{name: "delay without max", config: handlers.Chaos{DelayRate: 1}, expected: []string{}, err: handlers.ErrInvalidChaos},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			collected, err := runDecorator(t, handlers.ChaosDecorator(test.config, handlers.Map(identity)), "a", "b")
+			require.ErrorIs(t, err, test.err)
+			assert.Equal(t, test.expected, collected)
+		})
+	}
+}
+
+func TestChaosDecorator_Panic(t *testing.T) {
+	t.Parallel()
+
+	chaos := handlers.ChaosDecorator(handlers.Chaos{PanicRate: 1}, handlers.Map(identity))
+
+	require.PanicsWithValue(t, handlers.ErrInjectedFault, func() {
+		_, _ = runDecorator(t, chaos, "a")
+	})
+}
+
+func TestChaosDecorator_SeedIsDeterministic(t *testing.T) {
+	t.Parallel()
+
+	values := make([]string, 100)
+
+	for index := range values {
+		values[index] = strconv.Itoa(index)
+	}
+
+	run := func() []string {
+		chaos := handlers.ChaosDecorator(handlers.Chaos{Seed: 7, DropRate: 0.5}, handlers.Map(identity))
+
+		collected, err := runDecorator(t, chaos, values...)
+		require.NoError(t, err)
+
+		return collected
+	}
+
+	first := run()
+	assert.Equal(t, first, run())
+	assert.NotEmpty(t, first)
+	assert.Less(t, len(first), len(values))
+}
+
+func TestChaosDecorator_Delay(t *testing.T) {
+	t.Parallel()
+
+	clock := handlers.NewManualClock(time.Unix(0, 0))
+	config := handlers.Chaos{Clock: clock, DelayRate: 1, MaxDelay: time.Second}
+	running := startWindow(t, handlers.ChaosDecorator(config, handlers.Map(identity)))
+
+	running.input <- "a"
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	assert.Equal(t, "a", running.next(t))
+	assert.Empty(t, running.finish(t))
+}
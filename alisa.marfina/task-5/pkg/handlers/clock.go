@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"sync"
+	"time"
+)
+
+type Clock interface {
+	Now() time.Time
+	After(delay time.Duration) <-chan time.Time
+	NewTimer(delay time.Duration) Timer
+}
+
+// Timer fires once on C unless it is stopped first. Handlers that rearm a
+// timer on every message stop the previous one, so it is not left pending.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+}
+
+type systemClock struct{}
+
+type systemTimer struct {
+	timer *time.Timer
+}
+
+func (t systemTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t systemTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) After(delay time.Duration) <-chan time.Time {
+	return time.After(delay)
+}
+
+func (systemClock) NewTimer(delay time.Duration) Timer {
+	return systemTimer{timer: time.NewTimer(delay)}
+}
+
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// ManualClock only moves when Advance is called, which makes time-driven
+// handlers deterministic in tests.
+type ManualClock struct {
+	mu      sync.Mutex
+	changed *sync.Cond
+	now     time.Time
+	waiters []manualWaiter
+}
+
+type manualWaiter struct {
+	deadline time.Time
+	fire     chan time.Time
+}
+
+func NewManualClock(start time.Time) *ManualClock {
+	clock := &ManualClock{
+		mu:      sync.Mutex{},
+		changed: nil,
+		now:     start,
+		waiters: make([]manualWaiter, 0),
+	}
+	clock.changed = sync.NewCond(&clock.mu)
+
+	return clock
+}
+
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *ManualClock) After(delay time.Duration) <-chan time.Time {
+	return c.NewTimer(delay).C()
+}
+
+type manualTimer struct {
+	clock *ManualClock
+	fire  chan time.Time
+}
+
+func (t manualTimer) C() <-chan time.Time {
+	return t.fire
+}
+
+// Stop removes the timer from the pending ones and reports whether it had not
+// fired yet.
+func (t manualTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	for index, waiter := range t.clock.waiters {
+		if waiter.fire == t.fire {
+			t.clock.waiters = append(t.clock.waiters[:index], t.clock.waiters[index+1:]...)
+			t.clock.changed.Broadcast()
+
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *ManualClock) NewTimer(delay time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	fire := make(chan time.Time, 1)
+	timer := manualTimer{clock: c, fire: fire}
+
+	if delay <= 0 {
+		fire <- c.now
+
+		return timer
+	}
+
+	c.waiters = append(c.waiters, manualWaiter{deadline: c.now.Add(delay), fire: fire})
+	c.changed.Broadcast()
+
+	return timer
+}
+
+// Advance moves the clock forward and fires every timer that has come due.
+func (c *ManualClock) Advance(delay time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(delay)
+	pending := c.waiters[:0]
+
+	for _, waiter := range c.waiters {
+		if waiter.deadline.After(c.now) {
+			pending = append(pending, waiter)
+
+			continue
+		}
+
+		waiter.fire <- c.now
+	}
+
+	c.waiters = pending
+	c.changed.Broadcast()
+}
+
+// Pending reports how many timers are armed and have not fired or been stopped.
+func (c *ManualClock) Pending() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.waiters)
+}
+
+// BlockUntil waits until at least count timers are pending, so a test knows a
+// handler has armed its timer before it advances the clock.
+func (c *ManualClock) BlockUntil(count int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.waiters) < count {
+		c.changed.Wait()
+	}
+}
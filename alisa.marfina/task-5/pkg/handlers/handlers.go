@@ -16,110 +16,115 @@ const (
 )
 
 func PrefixDecoratorFunc(ctx context.Context, input, output chan string) error {
-	defer close(output)
-
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
-
-		case value, isOpen := <-input:
-			if !isOpen {
-				return nil
-			}
-
-			if strings.Contains(value, noDecoratorMessage) {
-				return ErrCannotBeDecorated
-			}
-
-			if !strings.HasPrefix(value, decoratorPrefix) {
-				value = decoratorPrefix + value
-			}
-
-			select {
-			case output <- value:
-			case <-ctx.Done():
-				return nil
-			}
-		}
-	}
+	return Decorator(decoratePrefix)(ctx, input, output)
 }
 
 func SeparatorFunc(ctx context.Context, input chan string, outputs []chan string) error {
-	defer func() {
-		for _, outputChannel := range outputs {
-			close(outputChannel)
-		}
-	}()
+	return Separator[string]()(ctx, input, outputs)
+}
 
-	if len(outputs) == 0 {
-		return nil
+func MultiplexerFunc(ctx context.Context, inputs []chan string, output chan string) error {
+	return Multiplexer(DropContaining(noMultiplexerMessage))(ctx, inputs, output)
+}
+
+func decoratePrefix(value string) (string, error) {
+	if strings.Contains(value, noDecoratorMessage) {
+		return "", ErrCannotBeDecorated
 	}
 
-	currentIndex := 0
+	if !strings.HasPrefix(value, decoratorPrefix) {
+		value = decoratorPrefix + value
+	}
 
-	for {
-		select {
-		case <-ctx.Done():
-			return nil
+	return value, nil
+}
 
-		case value, isOpen := <-input:
-			if !isOpen {
-				return nil
-			}
+func Decorator[T any](decorate func(T) (T, error)) func(context.Context, chan T, chan T) error {
+	return func(ctx context.Context, input, output chan T) error {
+		defer close(output)
 
+		for {
 			select {
-			case outputs[currentIndex] <- value:
-				currentIndex = (currentIndex + 1) % len(outputs)
 			case <-ctx.Done():
 				return nil
+
+			case value, isOpen := <-input:
+				if !isOpen {
+					return nil
+				}
+
+				decorated, err := decorate(value)
+				if err != nil {
+					return err
+				}
+
+				select {
+				case output <- decorated:
+				case <-ctx.Done():
+					return nil
+				}
 			}
 		}
 	}
 }
 
-func MultiplexerFunc(ctx context.Context, inputs []chan string, output chan string) error {
-	defer close(output)
+func Separator[T any]() func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		currentIndex := 0
 
-	if len(inputs) == 0 {
-		return nil
+		return separate(ctx, input, outputs, func(T) int {
+			index := currentIndex
+			currentIndex = (currentIndex + 1) % len(outputs)
+
+			return index
+		})
 	}
+}
 
-	var waitGroup sync.WaitGroup
+func Multiplexer[T any](drop func(T) bool) func(context.Context, []chan T, chan T) error {
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		defer close(output)
 
-	for _, inputChannel := range inputs {
-		channel := inputChannel
+		if len(inputs) == 0 {
+			return nil
+		}
 
-		waitGroup.Add(1)
+		var waitGroup sync.WaitGroup
 
-		go func() {
-			defer waitGroup.Done()
+		for _, inputChannel := range inputs {
+			channel := inputChannel
 
-			for {
-				select {
-				case <-ctx.Done():
-					return
+			waitGroup.Add(1)
 
-				case value, isOpen := <-channel:
-					if !isOpen {
-						return
-					}
-
-					if strings.Contains(value, noMultiplexerMessage) {
-						continue
-					}
+			go func() {
+				defer waitGroup.Done()
 
+				for {
 					select {
 					case <-ctx.Done():
 						return
-					case output <- value:
+
+					case value, isOpen := <-channel:
+						if !isOpen {
+							return
+						}
+
+						if drop != nil && drop(value) {
+							continue
+						}
+
+						select {
+						case <-ctx.Done():
+							return
+						case output <- value:
+						}
 					}
 				}
-			}
-		}()
-	}
+			}()
+		}
 
-	waitGroup.Wait()
+		waitGroup.Wait()
 
-	return nil
+		return nil
+	}
 }
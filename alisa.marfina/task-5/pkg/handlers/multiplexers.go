@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+func DropContaining(substring string) func(string) bool {
+	return func(value string) bool {
+		return strings.Contains(value, substring)
+	}
+}
+
+// PriorityMultiplexer always takes the next message from the lowest-indexed
+// input that has one ready, so input 0 is drained before any other input.
+func PriorityMultiplexer[T any](drop func(T) bool) func(context.Context, []chan T, chan T) error {
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		return merge(ctx, inputs, output, drop, func(int) int {
+			return 0
+		})
+	}
+}
+
+// RoundRobinMultiplexer takes turns between inputs that have messages ready,
+// starting after the input served last, so a busy input cannot starve the rest.
+func RoundRobinMultiplexer[T any](drop func(T) bool) func(context.Context, []chan T, chan T) error {
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		return merge(ctx, inputs, output, drop, func(last int) int {
+			return (last + 1) % len(inputs)
+		})
+	}
+}
+
+// OrderedMultiplexer restores the global order of messages whose inputs are each
+// ordered by sequence: it waits until every open input has a message and emits the
+// one with the lowest sequence number.
+func OrderedMultiplexer[T any](
+	sequence func(T) uint64,
+	drop func(T) bool,
+) func(context.Context, []chan T, chan T) error {
+	return func(ctx context.Context, inputs []chan T, output chan T) error {
+		defer close(output)
+
+		heads := make([]T, len(inputs))
+		hasHead := make([]bool, len(inputs))
+		isOpen := make([]bool, len(inputs))
+
+		for index := range inputs {
+			isOpen[index] = true
+		}
+
+		for {
+			for index, input := range inputs {
+				for isOpen[index] && !hasHead[index] {
+					select {
+					case <-ctx.Done():
+						return nil
+
+					case value, ok := <-input:
+						switch {
+						case !ok:
+							isOpen[index] = false
+						case drop == nil || !drop(value):
+							heads[index], hasHead[index] = value, true
+						}
+					}
+				}
+			}
+
+			lowest := -1
+
+			for index := range inputs {
+				if hasHead[index] && (lowest < 0 || sequence(heads[index]) < sequence(heads[lowest])) {
+					lowest = index
+				}
+			}
+
+			if lowest < 0 {
+				return nil
+			}
+
+			select {
+			case output <- heads[lowest]:
+				var zero T
+
+				heads[lowest], hasHead[lowest] = zero, false
+
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func merge[T any](
+	ctx context.Context,
+	inputs []chan T,
+	output chan T,
+	drop func(T) bool,
+	start func(last int) int,
+) error {
+	defer close(output)
+
+	isOpen := make([]bool, len(inputs))
+	openCount := len(inputs)
+
+	for index := range inputs {
+		isOpen[index] = true
+	}
+
+	last := len(inputs) - 1
+
+	for openCount > 0 {
+		index, value, ok, done := receiveReady(ctx, inputs, isOpen, start(last))
+		if done {
+			return nil
+		}
+
+		if !ok {
+			isOpen[index] = false
+			openCount--
+
+			continue
+		}
+
+		last = index
+
+		if drop != nil && drop(value) {
+			continue
+		}
+
+		select {
+		case output <- value:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// receiveReady polls open inputs starting from first without blocking and falls
+// back to waiting on all of them when none has a message ready.
+func receiveReady[T any](ctx context.Context, inputs []chan T, isOpen []bool, first int) (int, T, bool, bool) {
+	for offset := range inputs {
+		index := (first + offset) % len(inputs)
+
+		if !isOpen[index] {
+			continue
+		}
+
+		select {
+		case value, ok := <-inputs[index]:
+			return index, value, ok, false
+		default:
+		}
+	}
+
+	cases := make([]reflect.SelectCase, 0, len(inputs)+1)
+	indexes := make([]int, 0, len(inputs))
+
+	for index, input := range inputs {
+		if isOpen[index] {
+			cases = append(cases, reflect.SelectCase{
+				Dir:  reflect.SelectRecv,
+				Chan: reflect.ValueOf(input),
+				Send: reflect.Value{},
+			})
+			indexes = append(indexes, index)
+		}
+	}
+
+	cases = append(cases, reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+		Send: reflect.Value{},
+	})
+
+	chosen, received, ok := reflect.Select(cases)
+
+	var zero T
+
+	if chosen == len(indexes) {
+		return 0, zero, false, true
+	}
+
+	if !ok {
+		return indexes[chosen], zero, false, false
+	}
+
+	value, _ := received.Interface().(T)
+
+	return indexes[chosen], value, true, false
+}
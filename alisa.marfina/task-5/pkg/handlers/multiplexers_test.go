@@ -0,0 +1,111 @@
+package handlers_test
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func runMultiplexer(
+	t *testing.T,
+	multiplexer func(context.Context, []chan string, chan string) error,
+	inputValues ...[]string,
+) []string {
+	t.Helper()
+
+	inputs := make([]chan string, len(inputValues))
+	total := 0
+
+	for index, values := range inputValues {
+		inputs[index] = make(chan string, len(values))
+
+		for _, value := range values {
+			inputs[index] <- value
+		}
+
+		close(inputs[index])
+
+		total += len(values)
+	}
+
+	output := make(chan string, total)
+
+	require.NoError(t, multiplexer(context.Background(), inputs, output))
+
+	collected := make([]string, 0, total)
+
+	for value := range output {
+		collected = append(collected, value)
+	}
+
+	return collected
+}
+
+func sequenceOf(value string) uint64 {
+	sequence, _ := strconv.ParseUint(strings.SplitN(value, ":", 2)[0], 10, 64)
+
+	return sequence
+}
+
+func TestMultiplexerStrategies(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		multiplexer func(context.Context, []chan string, chan string) error
+		inputs      [][]string
+		expected    []string
+	}{
+		{
+			name:        "priority drains input 0 first",
+			multiplexer: handlers.PriorityMultiplexer[string](nil),
+			inputs:      [][]string{{"a0", "a1"}, {"b0", "b1"}},
+			expected:    []string{"a0", "a1", "b0", "b1"},
+		},
+		{
+			name:        "round robin alternates",
+			multiplexer: handlers.RoundRobinMultiplexer[string](nil),
+			inputs:      [][]string{{"a0", "a1", "a2"}, {"b0"}, {"c0", "c1"}},
+			expected:    []string{"a0", "b0", "c0", "a1", "c1", "a2"},
+		},
+		{
+			name:        "ordered merge by sequence",
+			multiplexer: handlers.OrderedMultiplexer[string](sequenceOf, nil),
+			inputs:      [][]string{{"1:a", "4:d", "5:e"}, {"2:b", "3:c", "6:f"}},
+			expected:    []string{"1:a", "2:b", "3:c", "4:d", "5:e", "6:f"},
+		},
+		{
+			name:        "drop predicate",
+			multiplexer: handlers.RoundRobinMultiplexer(handlers.DropContaining("skip")),
+			inputs:      [][]string{{"a", "skip me"}, {"b"}},
+			expected:    []string{"a", "b"},
+		},
+		{
+			name:        "ordered merge with drop",
+			multiplexer: handlers.OrderedMultiplexer(sequenceOf, handlers.DropContaining("skip")),
+			inputs:      [][]string{{"2:skip"}, {"1:a", "3:c"}},
+			expected:    []string{"1:a", "3:c"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, testCase.expected, runMultiplexer(t, testCase.multiplexer, testCase.inputs...))
+		})
+	}
+}
+
+func TestMultiplexerFunc_DropsNoMultiplexer(t *testing.T) {
+	t.Parallel()
+
+	collected := runMultiplexer(t, handlers.MultiplexerFunc, []string{"keep", "no multiplexer"}, []string{"too"})
+	assert.ElementsMatch(t, []string{"keep", "too"}, collected)
+}
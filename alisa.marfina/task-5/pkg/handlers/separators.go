@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+var ErrInvalidWeights = errors.New("invalid separator weights")
+
+func HashSeparator[T any](key func(T) string) func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		return separate(ctx, input, outputs, func(value T) int {
+			hash := fnv.New32a()
+			_, _ = hash.Write([]byte(key(value)))
+
+			return int(hash.Sum32() % uint32(len(outputs)))
+		})
+	}
+}
+
+// WeightedSeparator spreads messages with smooth weighted round-robin, so an
+// output with weight 2 gets two messages for every one sent to an output with weight 1.
+func WeightedSeparator[T any](weights ...int) func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		if len(weights) != len(outputs) {
+			closeAll(outputs)
+
+			return fmt.Errorf("%w: %d weights for %d outputs", ErrInvalidWeights, len(weights), len(outputs))
+		}
+
+		total := 0
+
+		for _, weight := range weights {
+			if weight <= 0 {
+				closeAll(outputs)
+
+				return fmt.Errorf("%w: weight %d", ErrInvalidWeights, weight)
+			}
+
+			total += weight
+		}
+
+		current := make([]int, len(weights))
+
+		return separate(ctx, input, outputs, func(T) int {
+			best := 0
+
+			for index, weight := range weights {
+				current[index] += weight
+
+				if current[index] > current[best] {
+					best = index
+				}
+			}
+
+			current[best] -= total
+
+			return best
+		})
+	}
+}
+
+func BroadcastSeparator[T any]() func(context.Context, chan T, []chan T) error {
+	return func(ctx context.Context, input chan T, outputs []chan T) error {
+		defer closeAll(outputs)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case value, isOpen := <-input:
+				if !isOpen {
+					return nil
+				}
+
+				for _, outputChannel := range outputs {
+					select {
+					case outputChannel <- value:
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
+
+func separate[T any](ctx context.Context, input chan T, outputs []chan T, pick func(T) int) error {
+	defer closeAll(outputs)
+
+	if len(outputs) == 0 {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case value, isOpen := <-input:
+			if !isOpen {
+				return nil
+			}
+
+			select {
+			case outputs[pick(value)] <- value:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
+
+func closeAll[T any](outputs []chan T) {
+	for _, outputChannel := range outputs {
+		close(outputChannel)
+	}
+}
@@ -0,0 +1,114 @@
+package handlers_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func runSeparator(
+	t *testing.T,
+	separator func(context.Context, chan string, []chan string) error,
+	values []string,
+	outputCount int,
+) ([][]string, error) {
+	t.Helper()
+
+	input := make(chan string, len(values))
+	outputs := make([]chan string, outputCount)
+
+	for index := range outputs {
+		outputs[index] = make(chan string, len(values))
+	}
+
+	for _, value := range values {
+		input <- value
+	}
+
+	close(input)
+
+	err := separator(context.Background(), input, outputs)
+
+	collected := make([][]string, outputCount)
+
+	for index, output := range outputs {
+		for value := range output {
+			collected[index] = append(collected[index], value)
+		}
+	}
+
+	return collected, err
+}
+
+func TestHashSeparator_SameKeySameOutput(t *testing.T) {
+	t.Parallel()
+
+	byUser := handlers.HashSeparator(func(value string) string {
+		return strings.SplitN(value, ":", 2)[0]
+	})
+
+	values := []string{"alice:1", "bob:1", "carol:1", "alice:2", "bob:2", "alice:3", "carol:2"}
+
+	collected, err := runSeparator(t, byUser, values, 3)
+	require.NoError(t, err)
+
+	owner := make(map[string]int)
+
+	for index, values := range collected {
+		for _, value := range values {
+			user := strings.SplitN(value, ":", 2)[0]
+
+			if previous, seen := owner[user]; seen {
+				assert.Equal(t, previous, index, "key %s split across outputs", user)
+			}
+
+			owner[user] = index
+		}
+	}
+
+	assert.Len(t, owner, 3)
+}
+
+func TestWeightedSeparator(t *testing.T) {
+	t.Parallel()
+
+	values := make([]string, 9)
+	for index := range values {
+		values[index] = "m"
+	}
+
+	collected, err := runSeparator(t, handlers.WeightedSeparator[string](2, 1), values, 2)
+	require.NoError(t, err)
+	assert.Len(t, collected[0], 6)
+	assert.Len(t, collected[1], 3)
+
+	_, err = runSeparator(t, handlers.WeightedSeparator[string](1), values, 2)
+	require.ErrorIs(t, err, handlers.ErrInvalidWeights)
+
+	_, err = runSeparator(t, handlers.WeightedSeparator[string](1, 0), values, 2)
+	require.ErrorIs(t, err, handlers.ErrInvalidWeights)
+}
+
+func TestBroadcastSeparator(t *testing.T) {
+	t.Parallel()
+
+	collected, err := runSeparator(t, handlers.BroadcastSeparator[string](), []string{"a", "b"}, 3)
+	require.NoError(t, err)
+
+	for _, values := range collected {
+		assert.Equal(t, []string{"a", "b"}, values)
+	}
+}
+
+func TestSeparatorFunc_RoundRobin(t *testing.T) {
+	t.Parallel()
+
+	collected, err := runSeparator(t, handlers.SeparatorFunc, []string{"a", "b", "c", "d", "e"}, 2)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"a", "c", "e"}, {"b", "d"}}, collected)
+}
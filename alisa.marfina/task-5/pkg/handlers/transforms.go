@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidThrottle = errors.New("invalid throttle")
+	ErrInvalidJSON     = errors.New("invalid json")
+	ErrMissingField    = errors.New("missing json field")
+)
+
+// Map is a Decorator for transformations that cannot fail.
+func Map[T any](transform func(T) T) func(context.Context, chan T, chan T) error {
+	return Decorator(func(value T) (T, error) {
+		return transform(value), nil
+	})
+}
+
+func Filter[T any](keep func(T) bool) func(context.Context, chan T, chan T) error {
+	return expand(func(value T) ([]T, error) {
+		if !keep(value) {
+			return nil, nil
+		}
+
+		return []T{value}, nil
+	})
+}
+
+// Dedupe drops a message when another one with the same key passed less than
+// ttl ago.
+func Dedupe[T any](clock Clock, ttl time.Duration, key func(T) string) func(context.Context, chan T, chan T) error {
+	type seen struct {
+		key     string
+		expires time.Time
+	}
+
+	return func(ctx context.Context, input, output chan T) error {
+		expiries := make(map[string]time.Time)
+
+		// Every key lives for the same ttl, so keys expire in the order they
+		// were seen and the oldest ones can be evicted from the front.
+		order := make([]seen, 0)
+
+		return expand(func(value T) ([]T, error) {
+			now := clock.Now()
+
+			for len(order) > 0 && !order[0].expires.After(now) {
+				delete(expiries, order[0].key)
+				order = order[1:]
+			}
+
+			id := key(value)
+			if _, isSeen := expiries[id]; isSeen {
+				return nil, nil
+			}
+
+			expiries[id] = now.Add(ttl)
+			order = append(order, seen{key: id, expires: expiries[id]})
+
+			return []T{value}, nil
+		})(ctx, input, output)
+	}
+}
+
+// Throttle passes messages through a token bucket that holds up to burst
+// tokens and gains one every interval. A message without a token waits for the
+// next one instead of being dropped.
+func Throttle[T any](clock Clock, interval time.Duration, burst int) func(context.Context, chan T, chan T) error {
+	return func(ctx context.Context, input, output chan T) error {
+		if interval <= 0 || burst <= 0 {
+			close(output)
+
+			return fmt.Errorf("%w: interval %v, burst %d", ErrInvalidThrottle, interval, burst)
+		}
+
+		tokens, refilled := burst, clock.Now()
+
+		refill := func() time.Duration {
+			elapsed := clock.Now().Sub(refilled)
+			gained := int(elapsed / interval)
+
+			if tokens+gained >= burst {
+				tokens, refilled = burst, clock.Now()
+
+				return 0
+			}
+
+			tokens += gained
+			refilled = refilled.Add(time.Duration(gained) * interval)
+
+			return interval - elapsed%interval
+		}
+
+		return expand(func(value T) ([]T, error) {
+			for wait := refill(); tokens == 0; wait = refill() {
+				timer := clock.NewTimer(wait)
+
+				select {
+				case <-timer.C():
+				case <-ctx.Done():
+					timer.Stop()
+
+					return nil, nil
+				}
+			}
+
+			tokens--
+
+			return []T{value}, nil
+		})(ctx, input, output)
+	}
+}
+
+// ExtractJSONField replaces a JSON object with one of its fields. Nested fields
+// are addressed with dots; string values are unquoted, any other value is kept
+// as JSON.
+func ExtractJSONField(path string) func(context.Context, chan string, chan string) error {
+	return Decorator(func(value string) (string, error) {
+		raw := json.RawMessage(value)
+
+		for _, name := range strings.Split(path, ".") {
+			var object map[string]json.RawMessage
+
+			if err := json.Unmarshal(raw, &object); err != nil {
+				return "", fmt.Errorf("%w: %w", ErrInvalidJSON, err)
+			}
+
+			field, isPresent := object[name]
+			if !isPresent {
+				return "", fmt.Errorf("%w: %q", ErrMissingField, path)
+			}
+
+			raw = field
+		}
+
+		var text string
+		if err := json.Unmarshal(raw, &text); err == nil {
+			return text, nil
+		}
+
+		return string(raw), nil
+	})
+}
+
+// SplitLines emits every line of a message separately. Line endings are
+// stripped and a trailing newline does not produce an empty line.
+func SplitLines() func(context.Context, chan string, chan string) error {
+	return expand(func(value string) ([]string, error) {
+		if value == "" {
+			return nil, nil
+		}
+
+		lines := strings.Split(strings.TrimSuffix(value, "\n"), "\n")
+
+		for index, line := range lines {
+			lines[index] = strings.TrimSuffix(line, "\r")
+		}
+
+		return lines, nil
+	})
+}
+
+// expand is a Decorator whose function turns each message into any number of
+// messages.
+func expand[T any](produce func(T) ([]T, error)) func(context.Context, chan T, chan T) error {
+	return func(ctx context.Context, input, output chan T) error {
+		defer close(output)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case value, isOpen := <-input:
+				if !isOpen {
+					return nil
+				}
+
+				produced, err := produce(value)
+				if err != nil {
+					return err
+				}
+
+				for _, item := range produced {
+					select {
+					case output <- item:
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package handlers_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+func runDecorator(
+	t *testing.T,
+	decorator func(context.Context, chan string, chan string) error,
+	values ...string,
+) ([]string, error) {
+	t.Helper()
+
+	input, output := make(chan string, len(values)), make(chan string, 10*len(values))
+
+	for _, value := range values {
+		input <- value
+	}
+
+	close(input)
+
+	err := decorator(context.Background(), input, output)
+
+	collected := make([]string, 0)
+
+	for value := range output {
+		collected = append(collected, value)
+	}
+
+	return collected, err
+}
+
+func TestTransforms(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		handler  func(context.Context, chan string, chan string) error
+		input    []string
+		expected []string
+	}{
+		{
+			name:     "map",
+			handler:  handlers.Map(strings.ToUpper),
+			input:    []string{"a", "b"},
+			expected: []string{"A", "B"},
+		},
+		{
+			name: "filter",
+			handler: handlers.Filter(func(value string) bool {
+				return value != "spam"
+			}),
+			input:    []string{"a", "spam", "b"},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "split lines",
+			handler:  handlers.SplitLines(),
+			input:    []string{"a\nb\r\n", "", "c\n\nd"},
+			expected: []string{"a", "b", "c", "", "d"},
+		},
+		{
+			name:     "json field",
+			handler:  handlers.ExtractJSONField("user.name"),
+			input:    []string{`{"user":{"name":"alice"}}`, `{"user":{"name":{"first":"bob"}}}`},
+			expected: []string{"alice", `{"first":"bob"}`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			collected, err := runDecorator(t, test.handler, test.input...)
+			require.NoError(t, err)
+			assert.Equal(t, test.expected, collected)
+		})
+	}
+}
+
+func TestExtractJSONField_Errors(t *testing.T) {
+	t.Parallel()
+
+	collected, err := runDecorator(t, handlers.ExtractJSONField("id"), `{"id":1}`, `{"name":"x"}`)
+	require.ErrorIs(t, err, handlers.ErrMissingField)
+	assert.Equal(t, []string{"1"}, collected)
+
+	_, err = runDecorator(t, handlers.ExtractJSONField("id"), "not json")
+	require.ErrorIs(t, err, handlers.ErrInvalidJSON)
+}
+
+func TestDedupe(t *testing.T) {
+	t.Parallel()
+
+	clock := handlers.NewManualClock(time.Unix(0, 0))
+	running := startWindow(t, handlers.Dedupe(clock, time.Minute, func(value string) string {
+		return value
+	}))
+
+	for _, value := range []string{"a", "b", "a"} {
+		running.input <- value
+	}
+
+	assert.Equal(t, "a", running.next(t))
+	assert.Equal(t, "b", running.next(t))
+
+	clock.Advance(time.Minute)
+
+	running.input <- "a"
+	running.input <- "a"
+	assert.Equal(t, []string{"a"}, running.finish(t))
+}
+
+func TestThrottle(t *testing.T) {
+	t.Parallel()
+
+	clock := handlers.NewManualClock(time.Unix(0, 0))
+	running := startWindow(t, handlers.Throttle[string](clock, time.Second, 2))
+
+	for _, value := range []string{"a", "b", "c"} {
+		running.input <- value
+	}
+
+	assert.Equal(t, "a", running.next(t))
+	assert.Equal(t, "b", running.next(t))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	assert.Equal(t, "c", running.next(t))
+
+	clock.Advance(10 * time.Second)
+
+	running.input <- "d"
+	running.input <- "e"
+	assert.Equal(t, []string{"d", "e"}, running.finish(t))
+}
+
+func TestThrottle_InvalidParameters(t *testing.T) {
+	t.Parallel()
+
+	_, err := runDecorator(t, handlers.Throttle[string](handlers.SystemClock(), 0, 1), "a")
+	require.ErrorIs(t, err, handlers.ErrInvalidThrottle)
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidWindow = errors.New("invalid window")
+
+// Join is an aggregate for string windows.
+func Join(separator string) func([]string) string {
+	return func(window []string) string {
+		return strings.Join(window, separator)
+	}
+}
+
+// SizeWindow emits one aggregate for every size messages. A shorter final window
+// is flushed when the input closes.
+func SizeWindow[T any](size int, aggregate func([]T) T) func(context.Context, chan T, chan T) error {
+	return func(ctx context.Context, input, output chan T) error {
+		defer close(output)
+
+		if size <= 0 {
+			return fmt.Errorf("%w: size %d", ErrInvalidWindow, size)
+		}
+
+		window := make([]T, 0, size)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case value, isOpen := <-input:
+				if !isOpen {
+					emitWindow(ctx, output, window, aggregate)
+
+					return nil
+				}
+
+				window = append(window, value)
+
+				if len(window) == size {
+					if !emitWindow(ctx, output, window, aggregate) {
+						return nil
+					}
+
+					window = make([]T, 0, size)
+				}
+			}
+		}
+	}
+}
+
+// TumblingWindow emits the aggregate of the messages received during each
+// period of width. Periods without messages emit nothing.
+func TumblingWindow[T any](clock Clock, width time.Duration, aggregate func([]T) T) func(context.Context, chan T, chan T) error {
+	return SlidingWindow(clock, width, width, aggregate)
+}
+
+// SlidingWindow emits, every slide, the aggregate of the messages received
+// during the last width, so windows overlap when width is larger than slide.
+// Messages are bucketed by slide, hence width must be a multiple of it.
+func SlidingWindow[T any](
+	clock Clock,
+	width time.Duration,
+	slide time.Duration,
+	aggregate func([]T) T,
+) func(context.Context, chan T, chan T) error {
+	return func(ctx context.Context, input, output chan T) error {
+		defer close(output)
+
+		if slide <= 0 || width < slide || width%slide != 0 {
+			return fmt.Errorf("%w: width %v, slide %v", ErrInvalidWindow, width, slide)
+		}
+
+		panes := make([][]T, width/slide)
+		current := 0
+		tick := clock.NewTimer(slide)
+
+		defer func() {
+			tick.Stop()
+		}()
+
+		collect := func() []T {
+			window := make([]T, 0)
+
+			for offset := 1; offset <= len(panes); offset++ {
+				window = append(window, panes[(current+offset)%len(panes)]...)
+			}
+
+			return window
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case value, isOpen := <-input:
+				if !isOpen {
+					emitWindow(ctx, output, collect(), aggregate)
+
+					return nil
+				}
+
+				panes[current] = append(panes[current], value)
+
+			case <-tick.C():
+				tick = clock.NewTimer(slide)
+
+				if !emitWindow(ctx, output, collect(), aggregate) {
+					return nil
+				}
+
+				current = (current + 1) % len(panes)
+				panes[current] = nil
+			}
+		}
+	}
+}
+
+// SessionWindow groups messages that arrive less than gap apart and emits the
+// aggregate once the input has been quiet for gap.
+func SessionWindow[T any](clock Clock, gap time.Duration, aggregate func([]T) T) func(context.Context, chan T, chan T) error {
+	return func(ctx context.Context, input, output chan T) error {
+		defer close(output)
+
+		if gap <= 0 {
+			return fmt.Errorf("%w: gap %v", ErrInvalidWindow, gap)
+		}
+
+		var (
+			session []T
+			quiet   Timer
+		)
+
+		defer func() {
+			if quiet != nil {
+				quiet.Stop()
+			}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return nil
+
+			case value, isOpen := <-input:
+				if !isOpen {
+					emitWindow(ctx, output, session, aggregate)
+
+					return nil
+				}
+
+				session = append(session, value)
+
+				if quiet != nil {
+					quiet.Stop()
+				}
+
+				quiet = clock.NewTimer(gap)
+
+			case <-timerC(quiet):
+				if !emitWindow(ctx, output, session, aggregate) {
+					return nil
+				}
+
+				session, quiet = nil, nil
+			}
+		}
+	}
+}
+
+// timerC returns nil, which blocks forever, while no timer is armed.
+func timerC(timer Timer) <-chan time.Time {
+	if timer == nil {
+		return nil
+	}
+
+	return timer.C()
+}
+
+// emitWindow sends the aggregate of a non-empty window and reports false when
+// ctx ended first.
+func emitWindow[T any](ctx context.Context, output chan T, window []T, aggregate func([]T) T) bool {
+	if len(window) == 0 {
+		return true
+	}
+
+	select {
+	case output <- aggregate(window):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,200 @@
+package handlers_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+type window struct {
+	input  chan string
+	output chan string
+	done   chan error
+}
+
+func startWindow(t *testing.T, handler func(context.Context, chan string, chan string) error) *window {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	running := &window{input: make(chan string), output: make(chan string, 10), done: make(chan error, 1)}
+
+	go func() {
+		running.done <- handler(ctx, running.input, running.output)
+	}()
+
+	return running
+}
+
+func (w *window) next(t *testing.T) string {
+	t.Helper()
+
+	select {
+	case value, isOpen := <-w.output:
+		require.True(t, isOpen, "output closed")
+
+		return value
+	case <-time.After(time.Second):
+		require.FailNow(t, "no window emitted")
+
+		return ""
+	}
+}
+
+func (w *window) finish(t *testing.T) []string {
+	t.Helper()
+
+	close(w.input)
+
+	rest := make([]string, 0)
+
+	for value := range w.output {
+		rest = append(rest, value)
+	}
+
+	require.NoError(t, <-w.done)
+
+	return rest
+}
+
+func TestSizeWindow(t *testing.T) {
+	t.Parallel()
+
+	running := startWindow(t, handlers.SizeWindow(2, handlers.Join(",")))
+
+	for _, value := range []string{"a", "b", "c"} {
+		running.input <- value
+	}
+
+	assert.Equal(t, "a,b", running.next(t))
+	assert.Equal(t, []string{"c"}, running.finish(t))
+}
+
+func TestTumblingWindow(t *testing.T) {
+	t.Parallel()
+
+	clock := handlers.NewManualClock(time.Unix(0, 0))
+	running := startWindow(t, handlers.TumblingWindow(clock, time.Second, handlers.Join(",")))
+
+	running.input <- "a"
+	running.input <- "b"
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	assert.Equal(t, "a,b", running.next(t))
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second)
+	clock.BlockUntil(1)
+
+	running.input <- "c"
+	assert.Equal(t, []string{"c"}, running.finish(t))
+	assert.Equal(t, 0, clock.Pending())
+}
+
+func TestSlidingWindow(t *testing.T) {
+	t.Parallel()
+
+	clock := handlers.NewManualClock(time.Unix(0, 0))
+	running := startWindow(t, handlers.SlidingWindow(clock, 3*time.Second, time.Second, handlers.Join(",")))
+
+	tick := func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}
+
+	running.input <- "a"
+	tick()
+	assert.Equal(t, "a", running.next(t))
+
+	running.input <- "b"
+	tick()
+	assert.Equal(t, "a,b", running.next(t))
+
+	tick()
+	assert.Equal(t, "a,b", running.next(t))
+
+	tick()
+	assert.Equal(t, "b", running.next(t))
+
+	tick()
+	clock.BlockUntil(1)
+
+	running.input <- "c"
+	assert.Equal(t, []string{"c"}, running.finish(t))
+	assert.Equal(t, 0, clock.Pending())
+}
+
+// armingClock reports every timer a handler arms, so a test knows the handler
+// is done with a message before it moves the clock.
+type armingClock struct {
+	*handlers.ManualClock
+	armed chan struct{}
+}
+
+func (c armingClock) NewTimer(delay time.Duration) handlers.Timer {
+	timer := c.ManualClock.NewTimer(delay)
+	c.armed <- struct{}{}
+
+	return timer
+}
+
+func TestSessionWindow(t *testing.T) {
+	t.Parallel()
+
+	clock := armingClock{ManualClock: handlers.NewManualClock(time.Unix(0, 0)), armed: make(chan struct{})}
+	running := startWindow(t, handlers.SessionWindow(clock, 5*time.Second, handlers.Join(",")))
+
+	running.input <- "a"
+	<-clock.armed
+	clock.Advance(3 * time.Second)
+
+	running.input <- "b"
+	<-clock.armed
+	assert.Equal(t, 1, clock.Pending())
+
+	clock.Advance(3 * time.Second)
+	clock.Advance(2 * time.Second)
+	assert.Equal(t, "a,b", running.next(t))
+	assert.Equal(t, 0, clock.Pending())
+
+	running.input <- "c"
+	<-clock.armed
+	assert.Equal(t, []string{"c"}, running.finish(t))
+	assert.Equal(t, 0, clock.Pending())
+}
+
+func TestWindows_InvalidParameters(t *testing.T) {
+	t.Parallel()
+
+	clock := handlers.NewManualClock(time.Unix(0, 0))
+	join := handlers.Join(",")
+
+	tests := []struct {
+		name    string
+		handler func(context.Context, chan string, chan string) error
+	}{
+		{name: "size", handler: handlers.SizeWindow(0, join)},
+		{name: "sliding", handler: handlers.SlidingWindow(clock, 3*time.Second, 2*time.Second, join)},
+		{name: "session", handler: handlers.SessionWindow(clock, 0, join)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			output := make(chan string)
+
+			err := test.handler(context.Background(), make(chan string), output)
+			require.ErrorIs(t, err, handlers.ErrInvalidWindow)
+
+			_, isOpen := <-output
+			assert.False(t, isOpen)
+		})
+	}
+}
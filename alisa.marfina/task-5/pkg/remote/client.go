@@ -0,0 +1,231 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+var ErrUnexpectedReply = errors.New("unexpected reply")
+
+// Client talks to a Server over HTTP.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+func (c *Client) channelURL(name string) string {
+	return c.baseURL + "/channels/" + url.PathEscape(name)
+}
+
+// Publish sends all messages in a single request, one escaped message per line.
+func (c *Client) Publish(ctx context.Context, channel string, messages ...string) error {
+	var body strings.Builder
+
+	for _, message := range messages {
+		body.WriteString(escape(message) + "\n")
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, c.channelURL(channel), strings.NewReader(body.String()))
+	if err != nil {
+		return fmt.Errorf("publish to %q: %w", channel, err)
+	}
+
+	request.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return fmt.Errorf("publish to %q: %w", channel, err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusNoContent {
+		return fmt.Errorf("publish to %q: %w", channel, statusError(response))
+	}
+
+	return nil
+}
+
+// Subscribe streams messages from an output channel until it is closed, ctx is
+// cancelled or the loop stops.
+func (c *Client) Subscribe(ctx context.Context, channel string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.channelURL(channel), nil)
+		if err != nil {
+			yield("", fmt.Errorf("subscribe to %q: %w", channel, err))
+
+			return
+		}
+
+		response, err := c.httpClient.Do(request)
+		if err != nil {
+			yield("", fmt.Errorf("subscribe to %q: %w", channel, err))
+
+			return
+		}
+		defer response.Body.Close()
+
+		if response.StatusCode != http.StatusOK {
+			yield("", fmt.Errorf("subscribe to %q: %w", channel, statusError(response)))
+
+			return
+		}
+
+		scanner := bufio.NewScanner(response.Body)
+
+		for scanner.Scan() {
+			if !yield(unescape(scanner.Text()), nil) {
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil && ctx.Err() == nil {
+			yield("", fmt.Errorf("subscribe to %q: %w", channel, err))
+		}
+	}
+}
+
+func statusError(response *http.Response) error {
+	message, _ := io.ReadAll(io.LimitReader(response.Body, 1<<10))
+
+	var code string
+
+	switch response.StatusCode {
+	case http.StatusNotFound:
+		code = codeNotFound
+	case http.StatusServiceUnavailable:
+		code = codeDraining
+	case http.StatusBadRequest:
+		code = codeBadRequest
+	default:
+		code = codeInternal
+	}
+
+	return codeError(code, strings.TrimSpace(string(message)))
+}
+
+// Conn talks to a Server over the line protocol. Publishing is synchronous, so a
+// Conn can be shared by goroutines; a subscription takes the connection over.
+type Conn struct {
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Scanner
+}
+
+func Dial(ctx context.Context, address string) (*Conn, error) {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("dial %s: %w", address, err)
+	}
+
+	return &Conn{
+		mu:     sync.Mutex{},
+		conn:   conn,
+		reader: bufio.NewScanner(conn),
+	}, nil
+}
+
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) Publish(channel string, message string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := c.command(commandPublish, channel, escape(message)); err != nil {
+		return fmt.Errorf("publish to %q: %w", channel, err)
+	}
+
+	return nil
+}
+
+// Subscribe streams messages from an output channel until the server reports it
+// closed or ctx is cancelled, after which the connection is closed.
+func (c *Conn) Subscribe(ctx context.Context, channel string) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		stop := context.AfterFunc(ctx, func() {
+			_ = c.conn.Close()
+		})
+		defer stop()
+		defer c.conn.Close()
+
+		if err := c.command(commandSubscribe, channel); err != nil {
+			yield("", fmt.Errorf("subscribe to %q: %w", channel, err))
+
+			return
+		}
+
+		for c.reader.Scan() {
+			kind, payload, _ := strings.Cut(c.reader.Text(), " ")
+
+			switch kind {
+			case replyEnd:
+				return
+			case replyMessage:
+				if !yield(unescape(payload), nil) {
+					return
+				}
+			default:
+				yield("", fmt.Errorf("subscribe to %q: %w: %q", channel, ErrUnexpectedReply, kind))
+
+				return
+			}
+		}
+
+		if err := c.reader.Err(); err != nil && ctx.Err() == nil {
+			yield("", fmt.Errorf("subscribe to %q: %w", channel, err))
+		}
+	}
+}
+
+func (c *Conn) command(parts ...string) error {
+	if _, err := fmt.Fprintln(c.conn, strings.Join(parts, " ")); err != nil {
+		return fmt.Errorf("write command: %w", err)
+	}
+
+	if !c.reader.Scan() {
+		if err := c.reader.Err(); err != nil {
+			return fmt.Errorf("read reply: %w", err)
+		}
+
+		return fmt.Errorf("read reply: %w", io.ErrUnexpectedEOF)
+	}
+
+	kind, rest, _ := strings.Cut(c.reader.Text(), " ")
+
+	switch kind {
+	case replyOK:
+		return nil
+	case replyError:
+		code, message, _ := strings.Cut(rest, " ")
+
+		return codeError(code, unescape(message))
+	default:
+		return fmt.Errorf("%w: %q", ErrUnexpectedReply, kind)
+	}
+}
@@ -0,0 +1,110 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+)
+
+var (
+	ErrUnknownChannel = errors.New("channel is not exposed")
+	ErrBadRequest     = errors.New("bad request")
+	ErrRemote         = errors.New("remote error")
+)
+
+// Line protocol commands and replies. Payloads are escaped so that a message
+// always occupies exactly one line.
+const (
+	commandPublish   = "PUB"
+	commandSubscribe = "SUB"
+	replyOK          = "OK"
+	replyError       = "ERR"
+	replyMessage     = "MSG"
+	replyEnd         = "END"
+
+	codeNotFound   = "not-found"
+	codeDraining   = "draining"
+	codeBadRequest = "bad-request"
+	codeInternal   = "internal"
+)
+
+type Pipeline interface {
+	SendContext(ctx context.Context, pipeName string, data string) error
+	RecvContext(ctx context.Context, pipeName string) (string, error)
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, "\r", `\r`)
+
+func escape(payload string) string {
+	return escaper.Replace(payload)
+}
+
+func unescape(line string) string {
+	var builder strings.Builder
+
+	builder.Grow(len(line))
+
+	for index := 0; index < len(line); index++ {
+		if line[index] != '\\' || index == len(line)-1 {
+			builder.WriteByte(line[index])
+
+			continue
+		}
+
+		index++
+
+		switch line[index] {
+		case 'n':
+			builder.WriteByte('\n')
+		case 'r':
+			builder.WriteByte('\r')
+		default:
+			builder.WriteByte(line[index])
+		}
+	}
+
+	return builder.String()
+}
+
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, ErrUnknownChannel), errors.Is(err, conveyer.ErrChanNotFound):
+		return codeNotFound
+	case errors.Is(err, conveyer.ErrDraining):
+		return codeDraining
+	case errors.Is(err, ErrBadRequest):
+		return codeBadRequest
+	default:
+		return codeInternal
+	}
+}
+
+func errorStatus(err error) int {
+	switch errorCode(err) {
+	case codeNotFound:
+		return http.StatusNotFound
+	case codeDraining:
+		return http.StatusServiceUnavailable
+	case codeBadRequest:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func codeError(code, message string) error {
+	switch code {
+	case codeNotFound:
+		return ErrUnknownChannel
+	case codeDraining:
+		return conveyer.ErrDraining
+	case codeBadRequest:
+		return ErrBadRequest
+	default:
+		return fmt.Errorf("%w: %s", ErrRemote, message)
+	}
+}
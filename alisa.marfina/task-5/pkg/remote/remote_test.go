@@ -0,0 +1,206 @@
+package remote_test
+
+import (
+	"bufio"
+	"context"
+	"iter"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+	"github.com/AliseMarfina/task-5/pkg/remote"
+)
+
+func startPipeline(t *testing.T) (*conveyer.Conveyer, *remote.Server) {
+	t.Helper()
+
+	conv := conveyer.New(10)
+	conv.RegisterDecorator(handlers.PrefixDecoratorFunc, "in", "out")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- conv.Run(ctx)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-errCh)
+	})
+
+	return conv, remote.NewServer(conv, []string{"in"}, []string{"out"})
+}
+
+func collect(t *testing.T, messages iter.Seq2[string, error], count int) []string {
+	t.Helper()
+
+	received := make([]string, 0, count)
+
+	for message, err := range messages {
+		require.NoError(t, err)
+
+		received = append(received, message)
+		if len(received) == count {
+			break
+		}
+	}
+
+	return received
+}
+
+func TestHTTP_PublishAndSubscribe(t *testing.T) {
+	t.Parallel()
+
+	_, server := startPipeline(t)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	client := remote.NewClient(httpServer.URL, httpServer.Client())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, client.Publish(ctx, "in", "a", "multi\nline", `back\slash`))
+
+	received := collect(t, client.Subscribe(ctx, "out"), 3)
+	assert.Equal(t, []string{"decorated: a", "decorated: multi\nline", `decorated: back\slash`}, received)
+}
+
+func TestHTTP_ServerSentEvents(t *testing.T) {
+	t.Parallel()
+
+	conv, server := startPipeline(t)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	require.NoError(t, conv.Send("in", "first\nsecond"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, httpServer.URL+"/channels/out", nil)
+	require.NoError(t, err)
+	request.Header.Set("Accept", "text/event-stream")
+
+	response, err := httpServer.Client().Do(request)
+	require.NoError(t, err)
+
+	defer response.Body.Close()
+
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+
+	reader := bufio.NewReader(response.Body)
+	lines := make([]string, 0, 3)
+
+	for range 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+
+		lines = append(lines, line)
+	}
+
+	assert.Equal(t, []string{"data: decorated: first\n", "data: second\n", "\n"}, lines)
+}
+
+func TestHTTP_Errors(t *testing.T) {
+	t.Parallel()
+
+	conv, server := startPipeline(t)
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	client := remote.NewClient(httpServer.URL, httpServer.Client())
+	ctx := context.Background()
+
+	require.ErrorIs(t, client.Publish(ctx, "out", "x"), remote.ErrUnknownChannel)
+
+	for _, err := range client.Subscribe(ctx, "in") {
+		require.ErrorIs(t, err, remote.ErrUnknownChannel)
+	}
+
+	conv.Drain()
+
+	require.ErrorIs(t, client.Publish(ctx, "in", "late"), conveyer.ErrDraining)
+}
+
+func startTCP(t *testing.T, server *remote.Server) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errCh := make(chan error, 1)
+
+	go func() {
+		errCh <- server.ServeTCP(ctx, listener)
+	}()
+
+	t.Cleanup(func() {
+		cancel()
+		assert.NoError(t, <-errCh)
+	})
+
+	return listener.Addr().String()
+}
+
+func TestTCP_PublishAndSubscribe(t *testing.T) {
+	t.Parallel()
+
+	conv, server := startPipeline(t)
+	address := startTCP(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	publisher, err := remote.Dial(ctx, address)
+	require.NoError(t, err)
+
+	defer publisher.Close()
+
+	require.NoError(t, publisher.Publish("in", "a"))
+	require.NoError(t, publisher.Publish("in", "b\nc"))
+	require.ErrorIs(t, publisher.Publish("missing", "x"), remote.ErrUnknownChannel)
+
+	subscriber, err := remote.Dial(ctx, address)
+	require.NoError(t, err)
+
+	received := collect(t, subscriber.Subscribe(ctx, "out"), 2)
+	assert.Equal(t, []string{"decorated: a", "decorated: b\nc"}, received)
+
+	conv.Drain()
+	require.ErrorIs(t, publisher.Publish("in", "late"), conveyer.ErrDraining)
+}
+
+func TestTCP_SubscriptionEndsWhenChannelCloses(t *testing.T) {
+	t.Parallel()
+
+	conv, server := startPipeline(t)
+	address := startTCP(t, server)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	require.NoError(t, conv.Send("in", "only"))
+	conv.Drain()
+
+	subscriber, err := remote.Dial(ctx, address)
+	require.NoError(t, err)
+
+	received := make([]string, 0, 1)
+
+	for message, err := range subscriber.Subscribe(ctx, "out") {
+		require.NoError(t, err)
+
+		received = append(received, message)
+	}
+
+	assert.Equal(t, []string{"decorated: only"}, received)
+}
@@ -0,0 +1,277 @@
+package remote
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+)
+
+const eventStreamType = "text/event-stream"
+
+// Server exposes the input channels of a pipeline for publishing and its output
+// channels for subscribing. Every message read from an output goes to exactly one
+// subscriber, the same as with Recv.
+type Server struct {
+	pipeline Pipeline
+	inputs   map[string]struct{}
+	outputs  map[string]struct{}
+	mux      *http.ServeMux
+}
+
+func NewServer(pipeline Pipeline, inputs []string, outputs []string) *Server {
+	server := &Server{
+		pipeline: pipeline,
+		inputs:   make(map[string]struct{}, len(inputs)),
+		outputs:  make(map[string]struct{}, len(outputs)),
+		mux:      http.NewServeMux(),
+	}
+
+	for _, name := range inputs {
+		server.inputs[name] = struct{}{}
+	}
+
+	for _, name := range outputs {
+		server.outputs[name] = struct{}{}
+	}
+
+	server.mux.HandleFunc("POST /channels/{name}", server.handlePublish)
+	server.mux.HandleFunc("GET /channels/{name}", server.handleSubscribe)
+
+	return server
+}
+
+func (s *Server) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	s.mux.ServeHTTP(writer, request)
+}
+
+func (s *Server) publish(ctx context.Context, name string, payload string) error {
+	if _, exposed := s.inputs[name]; !exposed {
+		return fmt.Errorf("%w: %q", ErrUnknownChannel, name)
+	}
+
+	if err := s.pipeline.SendContext(ctx, name, payload); err != nil {
+		return fmt.Errorf("publish to %q: %w", name, err)
+	}
+
+	return nil
+}
+
+func (s *Server) checkOutput(name string) error {
+	if _, exposed := s.outputs[name]; !exposed {
+		return fmt.Errorf("%w: %q", ErrUnknownChannel, name)
+	}
+
+	return nil
+}
+
+// handlePublish sends every line of the request body as a separate message.
+// Lines use the same escaping as the plain text subscription stream.
+func (s *Server) handlePublish(writer http.ResponseWriter, request *http.Request) {
+	name := request.PathValue("name")
+	scanner := bufio.NewScanner(request.Body)
+
+	for scanner.Scan() {
+		if err := s.publish(request.Context(), name, unescape(scanner.Text())); err != nil {
+			http.Error(writer, err.Error(), errorStatus(err))
+
+			return
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		http.Error(writer, fmt.Errorf("%w: %w", ErrBadRequest, err).Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+// handleSubscribe streams messages until the channel is closed or the client
+// goes away, as server-sent events when asked for and as text lines otherwise.
+func (s *Server) handleSubscribe(writer http.ResponseWriter, request *http.Request) {
+	name := request.PathValue("name")
+
+	if err := s.checkOutput(name); err != nil {
+		http.Error(writer, err.Error(), errorStatus(err))
+
+		return
+	}
+
+	events := strings.Contains(request.Header.Get("Accept"), eventStreamType)
+
+	if events {
+		writer.Header().Set("Content-Type", eventStreamType)
+	} else {
+		writer.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	}
+
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.WriteHeader(http.StatusOK)
+
+	controller := http.NewResponseController(writer)
+	_ = controller.Flush()
+
+	for {
+		payload, err := s.pipeline.RecvContext(request.Context(), name)
+		if err != nil {
+			return
+		}
+
+		if events {
+			err = writeEvent(writer, payload)
+		} else {
+			_, err = io.WriteString(writer, escape(payload)+"\n")
+		}
+
+		if err != nil || controller.Flush() != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(writer io.Writer, payload string) error {
+	var builder strings.Builder
+
+	for _, line := range strings.Split(payload, "\n") {
+		builder.WriteString("data: " + line + "\n")
+	}
+
+	builder.WriteString("\n")
+
+	_, err := io.WriteString(writer, builder.String())
+
+	return err
+}
+
+// ServeTCP accepts line protocol connections until ctx is cancelled:
+//
+//	PUB <channel> <payload>  ->  OK | ERR <code> <message>
+//	SUB <channel>            ->  MSG <payload> ... END | ERR <code> <message>
+func (s *Server) ServeTCP(ctx context.Context, listener net.Listener) error {
+	var connections sync.WaitGroup
+
+	defer connections.Wait()
+
+	stop := context.AfterFunc(ctx, func() {
+		_ = listener.Close()
+	})
+	defer stop()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			return fmt.Errorf("accept: %w", err)
+		}
+
+		connections.Add(1)
+
+		go func() {
+			defer connections.Done()
+
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	connCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	closeConn := context.AfterFunc(connCtx, func() {
+		_ = conn.Close()
+	})
+	defer closeConn()
+	defer conn.Close()
+
+	reader := bufio.NewScanner(conn)
+	writer := bufio.NewWriter(conn)
+
+	for reader.Scan() {
+		command, rest, _ := strings.Cut(reader.Text(), " ")
+
+		switch command {
+		case commandPublish:
+			name, payload, _ := strings.Cut(rest, " ")
+			reply(writer, s.publish(connCtx, name, unescape(payload)))
+
+		case commandSubscribe:
+			// A subscription takes the connection over, so a client closing its
+			// end is the only way to tell that it has gone away.
+			go func() {
+				_, _ = io.Copy(io.Discard, conn)
+
+				cancel()
+			}()
+
+			s.subscribeConn(connCtx, writer, rest)
+
+			return
+
+		default:
+			reply(writer, fmt.Errorf("%w: unknown command %q", ErrBadRequest, command))
+		}
+
+		if writer.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) subscribeConn(ctx context.Context, writer *bufio.Writer, name string) {
+	if err := s.checkOutput(name); err != nil {
+		reply(writer, err)
+		_ = writer.Flush()
+
+		return
+	}
+
+	reply(writer, nil)
+
+	if writer.Flush() != nil {
+		return
+	}
+
+	for {
+		payload, err := s.pipeline.RecvContext(ctx, name)
+
+		switch {
+		case errors.Is(err, conveyer.ErrChannelClosed):
+			fmt.Fprintln(writer, replyEnd)
+			_ = writer.Flush()
+
+			return
+
+		case err != nil:
+			return
+		}
+
+		fmt.Fprintln(writer, replyMessage, escape(payload))
+
+		if writer.Flush() != nil {
+			return
+		}
+	}
+}
+
+func reply(writer io.Writer, err error) {
+	if err == nil {
+		fmt.Fprintln(writer, replyOK)
+
+		return
+	}
+
+	fmt.Fprintln(writer, replyError, errorCode(err), escape(err.Error()))
+}
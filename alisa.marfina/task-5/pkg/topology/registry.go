@@ -0,0 +1,116 @@
+package topology
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/AliseMarfina/task-5/pkg/handlers"
+)
+
+var (
+	ErrUnknownHandler   = errors.New("unknown handler")
+	ErrHandlerDuplicate = errors.New("handler already registered")
+)
+
+const (
+	PrefixDecoratorName       = "prefix-decorator"
+	SeparatorName             = "separator"
+	HashSeparatorName         = "hash-separator"
+	BroadcastSeparatorName    = "broadcast-separator"
+	MultiplexerName           = "multiplexer"
+	PriorityMultiplexerName   = "priority-multiplexer"
+	RoundRobinMultiplexerName = "round-robin-multiplexer"
+)
+
+var dropNoMultiplexer = handlers.DropContaining("no multiplexer")
+
+type (
+	DecoratorFunc[T any]   func(context.Context, chan T, chan T) error
+	SeparatorFunc[T any]   func(context.Context, chan T, []chan T) error
+	MultiplexerFunc[T any] func(context.Context, []chan T, chan T) error
+)
+
+type Registry[T any] struct {
+	decorators   map[string]DecoratorFunc[T]
+	separators   map[string]SeparatorFunc[T]
+	multiplexers map[string]MultiplexerFunc[T]
+}
+
+func NewRegistry[T any]() *Registry[T] {
+	return &Registry[T]{
+		decorators:   make(map[string]DecoratorFunc[T]),
+		separators:   make(map[string]SeparatorFunc[T]),
+		multiplexers: make(map[string]MultiplexerFunc[T]),
+	}
+}
+
+func DefaultRegistry() *Registry[string] {
+	registry := NewRegistry[string]()
+	registry.decorators[PrefixDecoratorName] = handlers.PrefixDecoratorFunc
+	registry.separators[SeparatorName] = handlers.SeparatorFunc
+	registry.separators[HashSeparatorName] = handlers.HashSeparator(func(value string) string { return value })
+	registry.separators[BroadcastSeparatorName] = handlers.BroadcastSeparator[string]()
+	registry.multiplexers[MultiplexerName] = handlers.MultiplexerFunc
+	registry.multiplexers[PriorityMultiplexerName] = handlers.PriorityMultiplexer(dropNoMultiplexer)
+	registry.multiplexers[RoundRobinMultiplexerName] = handlers.RoundRobinMultiplexer(dropNoMultiplexer)
+
+	return registry
+}
+
+func (r *Registry[T]) AddDecorator(name string, handler DecoratorFunc[T]) error {
+	if _, exists := r.decorators[name]; exists {
+		return fmt.Errorf("decorator %q: %w", name, ErrHandlerDuplicate)
+	}
+
+	r.decorators[name] = handler
+
+	return nil
+}
+
+func (r *Registry[T]) AddSeparator(name string, handler SeparatorFunc[T]) error {
+	if _, exists := r.separators[name]; exists {
+		return fmt.Errorf("separator %q: %w", name, ErrHandlerDuplicate)
+	}
+
+	r.separators[name] = handler
+
+	return nil
+}
+
+func (r *Registry[T]) AddMultiplexer(name string, handler MultiplexerFunc[T]) error {
+	if _, exists := r.multiplexers[name]; exists {
+		return fmt.Errorf("multiplexer %q: %w", name, ErrHandlerDuplicate)
+	}
+
+	r.multiplexers[name] = handler
+
+	return nil
+}
+
+func (r *Registry[T]) Decorator(name string) (DecoratorFunc[T], error) {
+	handler, exists := r.decorators[name]
+	if !exists {
+		return nil, fmt.Errorf("decorator %q: %w", name, ErrUnknownHandler)
+	}
+
+	return handler, nil
+}
+
+func (r *Registry[T]) Separator(name string) (SeparatorFunc[T], error) {
+	handler, exists := r.separators[name]
+	if !exists {
+		return nil, fmt.Errorf("separator %q: %w", name, ErrUnknownHandler)
+	}
+
+	return handler, nil
+}
+
+func (r *Registry[T]) Multiplexer(name string) (MultiplexerFunc[T], error) {
+	handler, exists := r.multiplexers[name]
+	if !exists {
+		return nil, fmt.Errorf("multiplexer %q: %w", name, ErrUnknownHandler)
+	}
+
+	return handler, nil
+}
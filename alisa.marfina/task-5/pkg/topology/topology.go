@@ -0,0 +1,157 @@
+package topology
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+)
+
+var (
+	ErrUnknownNodeType    = errors.New("unknown node type")
+	ErrInvalidBufferSize  = errors.New("invalid buffer size")
+	ErrEmptyChannelName   = errors.New("empty channel name")
+	ErrUnexpectedChannels = errors.New("unexpected number of channels")
+)
+
+const (
+	NodeDecorator   = "decorator"
+	NodeSeparator   = "separator"
+	NodeMultiplexer = "multiplexer"
+)
+
+type Topology struct {
+	BufferSize int      `json:"buffer-size" yaml:"buffer-size"`
+	Inputs     []string `json:"inputs"      yaml:"inputs"`
+	Outputs    []string `json:"outputs"     yaml:"outputs"`
+	Nodes      []Node   `json:"nodes"       yaml:"nodes"`
+}
+
+type Node struct {
+	Type    string   `json:"type"    yaml:"type"`
+	Handler string   `json:"handler" yaml:"handler"`
+	Inputs  []string `json:"inputs"  yaml:"inputs"`
+	Outputs []string `json:"outputs" yaml:"outputs"`
+}
+
+func Parse(data []byte) (Topology, error) {
+	var topology Topology
+
+	if err := yaml.Unmarshal(data, &topology); err != nil {
+		return Topology{}, fmt.Errorf("failed to parse topology: %w", err)
+	}
+
+	return topology, nil
+}
+
+func ReadFile(path string) (Topology, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Topology{}, fmt.Errorf("failed to read topology file: %w", err)
+	}
+
+	return Parse(data)
+}
+
+func Load(path string) (*conveyer.Conveyer, error) {
+	topology, err := ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Build(topology, DefaultRegistry())
+}
+
+func Build[T any](topology Topology, registry *Registry[T]) (*conveyer.Typed[T], error) {
+	if topology.BufferSize < 0 {
+		return nil, fmt.Errorf("%w: %d", ErrInvalidBufferSize, topology.BufferSize)
+	}
+
+	conv := conveyer.NewTyped[T](topology.BufferSize)
+	conv.DeclareInputs(topology.Inputs...)
+	conv.DeclareOutputs(topology.Outputs...)
+
+	for index, node := range topology.Nodes {
+		if err := addNode(conv, registry, node); err != nil {
+			return nil, fmt.Errorf("node %d (%s %q): %w", index, node.Type, node.Handler, err)
+		}
+	}
+
+	if err := conv.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid topology: %w", err)
+	}
+
+	return conv, nil
+}
+
+func addNode[T any](conv *conveyer.Typed[T], registry *Registry[T], node Node) error {
+	for _, name := range append(append([]string{}, node.Inputs...), node.Outputs...) {
+		if name == "" {
+			return ErrEmptyChannelName
+		}
+	}
+
+	switch node.Type {
+	case NodeDecorator:
+		if err := expectChannels(node, true, true); err != nil {
+			return err
+		}
+
+		handler, err := registry.Decorator(node.Handler)
+		if err != nil {
+			return err
+		}
+
+		if _, err := conv.RegisterDecorator(handler, node.Inputs[0], node.Outputs[0]); err != nil {
+			return err
+		}
+
+	case NodeSeparator:
+		if err := expectChannels(node, true, false); err != nil {
+			return err
+		}
+
+		handler, err := registry.Separator(node.Handler)
+		if err != nil {
+			return err
+		}
+
+		if _, err := conv.RegisterSeparator(handler, node.Inputs[0], node.Outputs); err != nil {
+			return err
+		}
+
+	case NodeMultiplexer:
+		if err := expectChannels(node, false, true); err != nil {
+			return err
+		}
+
+		handler, err := registry.Multiplexer(node.Handler)
+		if err != nil {
+			return err
+		}
+
+		if _, err := conv.RegisterMultiplexer(handler, node.Inputs, node.Outputs[0]); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("%w: %q", ErrUnknownNodeType, node.Type)
+	}
+
+	return nil
+}
+
+func expectChannels(node Node, singleInput, singleOutput bool) error {
+	if len(node.Inputs) == 0 || (singleInput && len(node.Inputs) != 1) {
+		return fmt.Errorf("%w: %d inputs", ErrUnexpectedChannels, len(node.Inputs))
+	}
+
+	if len(node.Outputs) == 0 || (singleOutput && len(node.Outputs) != 1) {
+		return fmt.Errorf("%w: %d outputs", ErrUnexpectedChannels, len(node.Outputs))
+	}
+
+	return nil
+}
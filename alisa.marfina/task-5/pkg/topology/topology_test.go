@@ -0,0 +1,144 @@
+package topology_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/AliseMarfina/task-5/pkg/conveyer"
+	"github.com/AliseMarfina/task-5/pkg/topology"
+)
+
+const yamlTopology = `
+buffer-size: 4
+inputs: [in]
+outputs: [out]
+nodes:
+  - type: decorator
+    handler: prefix-decorator
+    inputs: [in]
+    outputs: [decorated]
+  - type: separator
+    handler: separator
+    inputs: [decorated]
+    outputs: [left, right]
+  - type: multiplexer
+    handler: multiplexer
+    inputs: [left, right]
+    outputs: [out]
+`
+
+const jsonTopology = `{
+  "buffer-size": 2,
+  "nodes": [
+    {"type": "decorator", "handler": "prefix-decorator", "inputs": ["in"], "outputs": ["out"]}
+  ]
+}`
+
+func TestLoad(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name    string
+		content string
+	}{
+		{name: "yaml", content: yamlTopology},
+		{name: "json", content: jsonTopology},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			path := filepath.Join(t.TempDir(), "topology")
+			require.NoError(t, os.WriteFile(path, []byte(testCase.content), 0o600))
+
+			conv, err := topology.Load(path)
+			require.NoError(t, err)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			go func() {
+				_ = conv.Run(ctx)
+			}()
+
+			require.NoError(t, conv.Send("in", "hello"))
+
+			value, err := conv.Recv("out")
+			require.NoError(t, err)
+			assert.Equal(t, "decorated: hello", value)
+		})
+	}
+}
+
+func TestBuild_Errors(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		node        topology.Node
+		expectedErr error
+	}{
+		{
+			name:        "unknown handler",
+			node:        topology.Node{Type: "decorator", Handler: "missing", Inputs: []string{"a"}, Outputs: []string{"b"}},
+			expectedErr: topology.ErrUnknownHandler,
+		},
+		{
+			name:        "unknown node type",
+			node:        topology.Node{Type: "router", Handler: "separator", Inputs: []string{"a"}, Outputs: []string{"b"}},
+			expectedErr: topology.ErrUnknownNodeType,
+		},
+		{
+			name:        "decorator with two outputs",
+			node:        topology.Node{Type: "decorator", Handler: "prefix-decorator", Inputs: []string{"a"}, Outputs: []string{"b", "c"}},
+			expectedErr: topology.ErrUnexpectedChannels,
+		},
+		{
+			name:        "empty channel name",
+			node:        topology.Node{Type: "separator", Handler: "separator", Inputs: []string{"a"}, Outputs: []string{""}},
+			expectedErr: topology.ErrEmptyChannelName,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := topology.Build(topology.Topology{
+				BufferSize: 1,
+				Nodes:      []topology.Node{testCase.node},
+			}, topology.DefaultRegistry())
+			require.ErrorIs(t, err, testCase.expectedErr)
+		})
+	}
+}
+
+func TestBuild_InvalidGraph(t *testing.T) {
+	t.Parallel()
+
+	topologyWithOrphan, err := topology.Parse([]byte(`
+inputs: [in]
+outputs: [out]
+nodes:
+  - {type: separator, handler: separator, inputs: [in], outputs: [out, orphan]}
+`))
+	require.NoError(t, err)
+
+	_, err = topology.Build(topologyWithOrphan, topology.DefaultRegistry())
+	require.ErrorIs(t, err, conveyer.ErrNoReader)
+}
+
+func TestRegistry_Duplicate(t *testing.T) {
+	t.Parallel()
+
+	registry := topology.DefaultRegistry()
+
+	err := registry.AddSeparator(topology.SeparatorName, nil)
+	require.ErrorIs(t, err, topology.ErrHandlerDuplicate)
+}